@@ -6,9 +6,13 @@ type PackType string
 
 const (
 	PackTypeSticker PackType = "sticker"
+	PackTypeMask    PackType = "mask"
 	PackTypeEmoji   PackType = "emoji"
 )
 
+// Pack is a set created by the bot. SourceSets holds the comma-separated
+// names of the sets it was copied from. Packs split from one copy share the
+// same GroupName.
 type Pack struct {
 	ID           int64     `db:"id"`
 	UserID       int64     `db:"user_id"`
@@ -17,6 +21,93 @@ type Pack struct {
 	PackType     PackType  `db:"pack_type"`
 	PackLink     string    `db:"pack_link"`
 	StickerCount int       `db:"sticker_count"`
+	SourceSets   string    `db:"source_sets"`
+	GroupName    string    `db:"group_name"`
 	CreatedAt    time.Time `db:"created_at"`
 }
 
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Job is a persisted pack-copy operation. Items holds the JSON-encoded
+// source items so the copy can be resumed after a restart. SourceSets holds
+// the comma-separated names of the sets the items come from and LeftOut the
+// comma-separated unique IDs of the items of those sets the copy leaves out
+// on purpose, such as items deselected by the user. BaseCount is the
+// number of items the set already held when the job started; jobs with a
+// non-zero BaseCount append to an existing set instead of creating one.
+// Jobs of a copy that was split over several sets share the same GroupName.
+type Job struct {
+	ID            int64     `db:"id"`
+	UserID        int64     `db:"user_id"`
+	LanguageCode  string    `db:"language_code"`
+	SetName       string    `db:"set_name"`
+	SetTitle      string    `db:"set_title"`
+	StickerType   string    `db:"sticker_type"`
+	Items         string    `db:"items"`
+	TotalCount    int       `db:"total_count"`
+	AddedCount    int       `db:"added_count"`
+	BaseCount     int       `db:"base_count"`
+	FallbackEmoji string    `db:"fallback_emoji"`
+	SourceSets    string    `db:"source_sets"`
+	LeftOut       string    `db:"left_out"`
+	GroupName     string    `db:"group_name"`
+	Status        JobStatus `db:"status"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+type JobItemStatus string
+
+const (
+	JobItemAdded  JobItemStatus = "added"
+	JobItemFailed JobItemStatus = "failed"
+)
+
+// JobItem is the recorded outcome of one source sticker of a job.
+type JobItem struct {
+	JobID        int64         `db:"job_id"`
+	Position     int           `db:"position"`
+	FileUniqueID string        `db:"file_unique_id"`
+	Status       JobItemStatus `db:"status"`
+	Reason       string        `db:"reason"`
+	Strategy     string        `db:"strategy"`
+	DurationMs   int64         `db:"duration_ms"`
+	CreatedAt    time.Time     `db:"created_at"`
+}
+
+// PackDeletion records a pack removed by its owner and whether its set was
+// deleted on Telegram or only dropped from the list.
+type PackDeletion struct {
+	ID                int64     `db:"id"`
+	UserID            int64     `db:"user_id"`
+	PackName          string    `db:"pack_name"`
+	PackTitle         string    `db:"pack_title"`
+	DeletedOnTelegram bool      `db:"deleted_on_telegram"`
+	CreatedAt         time.Time `db:"created_at"`
+}
+
+// PackItem links a source sticker to its copy in a pack. CopyUniqueID is
+// empty for source stickers the pack leaves out, so that syncing the pack
+// does not add them later.
+type PackItem struct {
+	UserID         int64     `db:"user_id"`
+	PackName       string    `db:"pack_name"`
+	SourceUniqueID string    `db:"source_unique_id"`
+	CopyUniqueID   string    `db:"copy_unique_id"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// StrategyStats summarises how copied stickers were handed to Telegram.
+type StrategyStats struct {
+	Strategy      string  `db:"strategy"`
+	Count         int     `db:"count"`
+	AvgDurationMs float64 `db:"avg_duration_ms"`
+}
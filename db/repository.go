@@ -25,19 +25,52 @@ func NewRepository(dbPath string) (*Repository, error) {
 		return nil, fmt.Errorf("failed to create schema: %w", err)
 	}
 
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
 	return &Repository{db: db}, nil
 }
 
+// migrate runs the Migrations the database has not seen yet, each in its own
+// transaction together with the bump of its schema version.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	for i := version; i < len(Migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.Exec(Migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to run migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record schema version %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
 func (r *Repository) Close() error {
 	return r.db.Close()
 }
 
 func (r *Repository) CreatePack(pack *Pack) error {
 	query := `
-		INSERT INTO packs (user_id, pack_name, pack_title, pack_type, pack_link, sticker_count)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO packs (user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, source_sets, group_name)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := r.db.Exec(query, pack.UserID, pack.PackName, pack.PackTitle, pack.PackType, pack.PackLink, pack.StickerCount)
+	result, err := r.db.Exec(query, pack.UserID, pack.PackName, pack.PackTitle, pack.PackType, pack.PackLink, pack.StickerCount, pack.SourceSets, pack.GroupName)
 	if err != nil {
 		return fmt.Errorf("failed to create pack: %w", err)
 	}
@@ -53,7 +86,7 @@ func (r *Repository) CreatePack(pack *Pack) error {
 
 func (r *Repository) GetPacksByUserID(userID int64) ([]Pack, error) {
 	query := `
-		SELECT id, user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, created_at
+		SELECT id, user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, source_sets, group_name, created_at
 		FROM packs
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -67,7 +100,7 @@ func (r *Repository) GetPacksByUserID(userID int64) ([]Pack, error) {
 	var packs []Pack
 	for rows.Next() {
 		var pack Pack
-		err := rows.Scan(&pack.ID, &pack.UserID, &pack.PackName, &pack.PackTitle, &pack.PackType, &pack.PackLink, &pack.StickerCount, &pack.CreatedAt)
+		err := rows.Scan(&pack.ID, &pack.UserID, &pack.PackName, &pack.PackTitle, &pack.PackType, &pack.PackLink, &pack.StickerCount, &pack.SourceSets, &pack.GroupName, &pack.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan pack: %w", err)
 		}
@@ -83,13 +116,13 @@ func (r *Repository) GetPacksByUserID(userID int64) ([]Pack, error) {
 
 func (r *Repository) GetPackByID(packID, userID int64) (*Pack, error) {
 	query := `
-		SELECT id, user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, created_at
+		SELECT id, user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, source_sets, group_name, created_at
 		FROM packs
 		WHERE id = ? AND user_id = ?
 	`
 	var pack Pack
 	err := r.db.QueryRow(query, packID, userID).Scan(
-		&pack.ID, &pack.UserID, &pack.PackName, &pack.PackTitle, &pack.PackType, &pack.PackLink, &pack.StickerCount, &pack.CreatedAt,
+		&pack.ID, &pack.UserID, &pack.PackName, &pack.PackTitle, &pack.PackType, &pack.PackLink, &pack.StickerCount, &pack.SourceSets, &pack.GroupName, &pack.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -102,6 +135,14 @@ func (r *Repository) GetPackByID(packID, userID int64) (*Pack, error) {
 }
 
 func (r *Repository) DeletePack(packID, userID int64) error {
+	itemsQuery := `
+		DELETE FROM pack_items
+		WHERE user_id = ? AND pack_name IN (SELECT pack_name FROM packs WHERE id = ? AND user_id = ?)
+	`
+	if _, err := r.db.Exec(itemsQuery, userID, packID, userID); err != nil {
+		return fmt.Errorf("failed to delete pack items: %w", err)
+	}
+
 	query := `DELETE FROM packs WHERE id = ? AND user_id = ?`
 	result, err := r.db.Exec(query, packID, userID)
 	if err != nil {
@@ -168,6 +209,20 @@ func (r *Repository) GetAllActiveUsers() ([]User, error) {
 	return users, nil
 }
 
+// GetUserLanguage returns the last known language of a user, or an empty
+// string for unknown users.
+func (r *Repository) GetUserLanguage(userID int64) (string, error) {
+	var lang sql.NullString
+	err := r.db.QueryRow(`SELECT language_code FROM users WHERE user_id = ?`, userID).Scan(&lang)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user language: %w", err)
+	}
+	return lang.String, nil
+}
+
 func (r *Repository) GetUserCount() (int, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM users WHERE is_active = 1`
@@ -177,3 +232,413 @@ func (r *Repository) GetUserCount() (int, error) {
 	}
 	return count, nil
 }
+
+func (r *Repository) CreateJob(job *Job) error {
+	query := `
+		INSERT INTO jobs (user_id, language_code, set_name, set_title, sticker_type, items, total_count, added_count, base_count, fallback_emoji, source_sets, left_out, group_name, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := r.db.Exec(query, job.UserID, job.LanguageCode, job.SetName, job.SetTitle, job.StickerType, job.Items, job.TotalCount, job.AddedCount, job.BaseCount, job.FallbackEmoji, job.SourceSets, job.LeftOut, job.GroupName, job.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	job.ID = id
+	return nil
+}
+
+func (r *Repository) GetUnfinishedJobs() ([]Job, error) {
+	query := `
+		SELECT id, user_id, language_code, set_name, set_title, sticker_type, items, total_count, added_count, base_count, fallback_emoji, source_sets, left_out, group_name, status, created_at, updated_at
+		FROM jobs
+		WHERE status IN (?, ?)
+		ORDER BY id ASC
+	`
+	rows, err := r.db.Query(query, JobStatusPending, JobStatusRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		err := rows.Scan(&job.ID, &job.UserID, &job.LanguageCode, &job.SetName, &job.SetTitle, &job.StickerType, &job.Items, &job.TotalCount, &job.AddedCount, &job.BaseCount, &job.FallbackEmoji, &job.SourceSets, &job.LeftOut, &job.GroupName, &job.Status, &job.CreatedAt, &job.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *Repository) UpdateJobStatus(jobID int64, status JobStatus) error {
+	query := `UPDATE jobs SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := r.db.Exec(query, status, jobID); err != nil {
+		return fmt.Errorf("failed to update job status: %w", err)
+	}
+	return nil
+}
+
+// RecordJobItem stores the outcome of the sticker at position in the job.
+func (r *Repository) RecordJobItem(item *JobItem) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT OR REPLACE INTO job_items (job_id, position, file_unique_id, status, reason, strategy, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, item.JobID, item.Position, item.FileUniqueID, item.Status, item.Reason, item.Strategy, item.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to record job item: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE jobs
+		SET added_count = (SELECT COUNT(*) FROM job_items WHERE job_id = ? AND status = ?), updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, item.JobID, JobItemAdded, item.JobID)
+	if err != nil {
+		return fmt.Errorf("failed to update job progress: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetJobItemStatuses returns the recorded status of every processed position
+// of a job.
+func (r *Repository) GetJobItemStatuses(jobID int64) (map[int]JobItemStatus, error) {
+	rows, err := r.db.Query(`SELECT position, status FROM job_items WHERE job_id = ?`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job items: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[int]JobItemStatus)
+	for rows.Next() {
+		var position int
+		var status JobItemStatus
+		if err := rows.Scan(&position, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan job item: %w", err)
+		}
+		statuses[position] = status
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job items: %w", err)
+	}
+
+	return statuses, nil
+}
+
+func (r *Repository) GetJobByID(jobID, userID int64) (*Job, error) {
+	query := `
+		SELECT id, user_id, language_code, set_name, set_title, sticker_type, items, total_count, added_count, base_count, fallback_emoji, source_sets, left_out, group_name, status, created_at, updated_at
+		FROM jobs
+		WHERE id = ? AND user_id = ?
+	`
+	var job Job
+	err := r.db.QueryRow(query, jobID, userID).Scan(
+		&job.ID, &job.UserID, &job.LanguageCode, &job.SetName, &job.SetTitle, &job.StickerType, &job.Items, &job.TotalCount, &job.AddedCount, &job.BaseCount, &job.FallbackEmoji, &job.SourceSets, &job.LeftOut, &job.GroupName, &job.Status, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return &job, nil
+}
+
+func (r *Repository) DeletePackByName(packName string, userID int64) error {
+	if _, err := r.db.Exec(`DELETE FROM pack_items WHERE pack_name = ? AND user_id = ?`, packName, userID); err != nil {
+		return fmt.Errorf("failed to delete pack items: %w", err)
+	}
+
+	query := `DELETE FROM packs WHERE pack_name = ? AND user_id = ?`
+	if _, err := r.db.Exec(query, packName, userID); err != nil {
+		return fmt.Errorf("failed to delete pack: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) UpdatePackTitle(packID, userID int64, title string) error {
+	query := `UPDATE packs SET pack_title = ? WHERE id = ? AND user_id = ?`
+	if _, err := r.db.Exec(query, title, packID, userID); err != nil {
+		return fmt.Errorf("failed to update pack title: %w", err)
+	}
+	return nil
+}
+
+// RecordPackDeletion stores how a pack was deleted.
+func (r *Repository) RecordPackDeletion(deletion *PackDeletion) error {
+	query := `
+		INSERT INTO pack_deletions (user_id, pack_name, pack_title, deleted_on_telegram)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := r.db.Exec(query, deletion.UserID, deletion.PackName, deletion.PackTitle, deletion.DeletedOnTelegram)
+	if err != nil {
+		return fmt.Errorf("failed to record pack deletion: %w", err)
+	}
+	return nil
+}
+
+// GetSyncablePacks returns every pack that records the sets it was copied
+// from.
+func (r *Repository) GetSyncablePacks() ([]Pack, error) {
+	query := `
+		SELECT id, user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, source_sets, group_name, created_at
+		FROM packs
+		WHERE source_sets != ''
+		ORDER BY id ASC
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query packs: %w", err)
+	}
+	defer rows.Close()
+
+	var packs []Pack
+	for rows.Next() {
+		var pack Pack
+		err := rows.Scan(&pack.ID, &pack.UserID, &pack.PackName, &pack.PackTitle, &pack.PackType, &pack.PackLink, &pack.StickerCount, &pack.SourceSets, &pack.GroupName, &pack.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pack: %w", err)
+		}
+		packs = append(packs, pack)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating packs: %w", err)
+	}
+
+	return packs, nil
+}
+
+// GetGroupPacks returns the packs of a copy split over several sets, in the
+// order they were created.
+func (r *Repository) GetGroupPacks(userID int64, groupName string) ([]Pack, error) {
+	query := `
+		SELECT id, user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, source_sets, group_name, created_at
+		FROM packs
+		WHERE user_id = ? AND group_name = ?
+		ORDER BY id ASC
+	`
+	rows, err := r.db.Query(query, userID, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query packs: %w", err)
+	}
+	defer rows.Close()
+
+	var packs []Pack
+	for rows.Next() {
+		var pack Pack
+		err := rows.Scan(&pack.ID, &pack.UserID, &pack.PackName, &pack.PackTitle, &pack.PackType, &pack.PackLink, &pack.StickerCount, &pack.SourceSets, &pack.GroupName, &pack.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pack: %w", err)
+		}
+		packs = append(packs, pack)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating packs: %w", err)
+	}
+
+	return packs, nil
+}
+
+// SavePackItems records which copy belongs to which source sticker,
+// replacing earlier records of the same source stickers. Items without a copy
+// never replace a recorded copy.
+func (r *Repository) SavePackItems(items []PackItem) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO pack_items (user_id, pack_name, source_unique_id, copy_unique_id)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, pack_name, source_unique_id) DO UPDATE
+		SET copy_unique_id = excluded.copy_unique_id, created_at = CURRENT_TIMESTAMP
+		WHERE excluded.copy_unique_id != ''
+	`
+	for _, item := range items {
+		if _, err := tx.Exec(query, item.UserID, item.PackName, item.SourceUniqueID, item.CopyUniqueID); err != nil {
+			return fmt.Errorf("failed to save pack item: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPackItems maps the source stickers of a pack to their copies. Source
+// stickers the pack leaves out map to an empty string.
+func (r *Repository) GetPackItems(userID int64, packName string) (map[string]string, error) {
+	query := `SELECT source_unique_id, copy_unique_id FROM pack_items WHERE user_id = ? AND pack_name = ?`
+	rows, err := r.db.Query(query, userID, packName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[string]string)
+	for rows.Next() {
+		var sourceID, copyID string
+		if err := rows.Scan(&sourceID, &copyID); err != nil {
+			return nil, fmt.Errorf("failed to scan pack item: %w", err)
+		}
+		items[sourceID] = copyID
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pack items: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetCoveredItems returns the unique IDs of the source stickers recorded for a
+// pack, copied or left out. For a pack that is part of a split copy the items
+// of every part of groupName count.
+func (r *Repository) GetCoveredItems(userID int64, packName, groupName string) (map[string]bool, error) {
+	query := `
+		SELECT source_unique_id FROM pack_items
+		WHERE user_id = ? AND (pack_name = ? OR pack_name IN (
+			SELECT pack_name FROM packs WHERE user_id = ? AND group_name = ? AND group_name != ''
+		))
+	`
+	rows, err := r.db.Query(query, userID, packName, userID, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack items: %w", err)
+	}
+	defer rows.Close()
+
+	covered := make(map[string]bool)
+	for rows.Next() {
+		var sourceID string
+		if err := rows.Scan(&sourceID); err != nil {
+			return nil, fmt.Errorf("failed to scan pack item: %w", err)
+		}
+		covered[sourceID] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pack items: %w", err)
+	}
+
+	return covered, nil
+}
+
+// DeletePackItem forgets the copy of a source sticker removed from a pack.
+func (r *Repository) DeletePackItem(userID int64, packName, sourceUniqueID string) error {
+	query := `DELETE FROM pack_items WHERE user_id = ? AND pack_name = ? AND source_unique_id = ?`
+	if _, err := r.db.Exec(query, userID, packName, sourceUniqueID); err != nil {
+		return fmt.Errorf("failed to delete pack item: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetFailedJobItems(jobID int64) ([]JobItem, error) {
+	query := `
+		SELECT job_id, position, file_unique_id, status, reason, strategy, duration_ms, created_at
+		FROM job_items
+		WHERE job_id = ? AND status = ?
+		ORDER BY position ASC
+	`
+	rows, err := r.db.Query(query, jobID, JobItemFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []JobItem
+	for rows.Next() {
+		var item JobItem
+		err := rows.Scan(&item.JobID, &item.Position, &item.FileUniqueID, &item.Status, &item.Reason, &item.Strategy, &item.DurationMs, &item.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job items: %w", err)
+	}
+
+	return items, nil
+}
+
+// ClearFailedJobItems forgets the failed items of a job so they are
+// attempted again on its next run.
+func (r *Repository) ClearFailedJobItems(jobID int64) error {
+	query := `DELETE FROM job_items WHERE job_id = ? AND status = ?`
+	if _, err := r.db.Exec(query, jobID, JobItemFailed); err != nil {
+		return fmt.Errorf("failed to clear failed job items: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) UpsertPack(pack *Pack) error {
+	query := `
+		INSERT INTO packs (user_id, pack_name, pack_title, pack_type, pack_link, sticker_count, source_sets, group_name)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, pack_name) DO UPDATE SET
+			pack_title = excluded.pack_title,
+			sticker_count = excluded.sticker_count,
+			source_sets = excluded.source_sets,
+			group_name = excluded.group_name
+	`
+	_, err := r.db.Exec(query, pack.UserID, pack.PackName, pack.PackTitle, pack.PackType, pack.PackLink, pack.StickerCount, pack.SourceSets, pack.GroupName)
+	if err != nil {
+		return fmt.Errorf("failed to upsert pack: %w", err)
+	}
+	return nil
+}
+
+// GetStrategyStats returns how many stickers were added with each copy
+// strategy and how long one took on average.
+func (r *Repository) GetStrategyStats() ([]StrategyStats, error) {
+	query := `
+		SELECT strategy, COUNT(*), AVG(duration_ms)
+		FROM job_items
+		WHERE status = ? AND strategy != ''
+		GROUP BY strategy
+		ORDER BY strategy
+	`
+	rows, err := r.db.Query(query, JobItemAdded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query strategy stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []StrategyStats
+	for rows.Next() {
+		var stat StrategyStats
+		if err := rows.Scan(&stat.Strategy, &stat.Count, &stat.AvgDurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan strategy stats: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating strategy stats: %w", err)
+	}
+
+	return stats, nil
+}
@@ -29,5 +29,69 @@ CREATE TABLE IF NOT EXISTS users (
 
 CREATE INDEX IF NOT EXISTS idx_users_last_seen ON users(last_seen_at);
 CREATE INDEX IF NOT EXISTS idx_users_is_active ON users(is_active);
+
+CREATE TABLE IF NOT EXISTS jobs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	language_code TEXT,
+	set_name TEXT NOT NULL,
+	set_title TEXT NOT NULL,
+	sticker_type TEXT NOT NULL,
+	items TEXT NOT NULL,
+	total_count INTEGER NOT NULL,
+	added_count INTEGER NOT NULL DEFAULT 0,
+	base_count INTEGER NOT NULL DEFAULT 0,
+	fallback_emoji TEXT NOT NULL DEFAULT '',
+	source_sets TEXT NOT NULL DEFAULT '',
+	left_out TEXT NOT NULL DEFAULT '',
+	group_name TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+
+CREATE TABLE IF NOT EXISTS job_items (
+	job_id INTEGER NOT NULL,
+	position INTEGER NOT NULL,
+	file_unique_id TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'added',
+	reason TEXT NOT NULL DEFAULT '',
+	strategy TEXT NOT NULL DEFAULT '',
+	duration_ms INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(job_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS pack_deletions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	pack_name TEXT NOT NULL,
+	pack_title TEXT NOT NULL,
+	deleted_on_telegram INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS pack_items (
+	user_id INTEGER NOT NULL,
+	pack_name TEXT NOT NULL,
+	source_unique_id TEXT NOT NULL,
+	copy_unique_id TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(user_id, pack_name, source_unique_id)
+);
+
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER NOT NULL
+);
 `
 
+// Migrations upgrade the tables of databases created by earlier versions of
+// the bot. They run in order after Schema and each one runs only once: the
+// number of migrations applied so far is kept in schema_version. New
+// migrations go at the end of the list.
+var Migrations = []string{
+	`ALTER TABLE packs ADD COLUMN source_sets TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE packs ADD COLUMN group_name TEXT NOT NULL DEFAULT ''`,
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"log"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// HandleAddStart picks one of the user's packs to append stickers to.
+func HandleAddStart(ctx tg.Context, packID int64, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	if sessions.Get(userID).State == services.StateCopying {
+		return ctx.Send(utils.T(lang, "copy-in-progress"))
+	}
+
+	pack, err := repo.GetPackByID(packID, userID)
+	if err != nil {
+		log.Printf("Error getting pack %d for user %d: %v", packID, userID, err)
+		return ctx.Send(utils.T(lang, "error"))
+	}
+	if pack == nil {
+		return ctx.Send(utils.T(lang, "add-not-found"))
+	}
+
+	stickerSet, err := services.FetchSet(bot, pack.PackName)
+	if err != nil {
+		log.Printf("Error fetching pack %s to append to: %v", pack.PackName, err)
+		return ctx.Send(utils.T(lang, "add-set-missing"))
+	}
+
+	capacity := services.SetCapacity(stickerSet.Type)
+	if len(stickerSet.Stickers) >= capacity {
+		return ctx.Send(utils.T(lang, "add-pack-full", capacity))
+	}
+
+	sessions.Set(userID, &services.Session{
+		State:      services.StateWaitingForAddSource,
+		TargetPack: pack,
+	})
+
+	return ctx.Send(utils.T(lang, "add-source", pack.PackTitle, capacity-len(stickerSet.Stickers)))
+}
+
+// HandleAddText appends the packs linked in a message, or its custom emoji,
+// to the pack chosen with /add.
+func HandleAddText(ctx tg.Context, text string, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+
+	if packNames := utils.ExtractPackNames(text); len(packNames) > 0 {
+		var stickers []tg.Sticker
+		var sources []types.StickerSet
+		for _, packName := range packNames {
+			stickerSet, err := services.FetchSet(bot, packName)
+			if err != nil {
+				log.Printf("Error fetching pack %s to append: %v", packName, err)
+				ctx.Send(utils.T(lang, "merge-set-missing", packName))
+				continue
+			}
+			stickers = append(stickers, stickerSet.Stickers...)
+			sources = append(sources, *stickerSet)
+		}
+
+		if len(stickers) == 0 {
+			return nil
+		}
+		return appendStickers(ctx, lang, stickers, sources, bot, sessions, repo)
+	}
+
+	if ids := utils.CustomEmojiIDs(ctx.Message()); len(ids) > 0 {
+		stickers, err := bot.CustomEmojiStickers(ids)
+		if err != nil {
+			log.Printf("Error fetching custom emoji %v: %v", ids, err)
+			return ctx.Send(utils.T(lang, "error"))
+		}
+
+		stickers, sources := stickerSources(ctx, lang, bot, stickers)
+		if len(stickers) == 0 {
+			return nil
+		}
+		return appendStickers(ctx, lang, stickers, sources, bot, sessions, repo)
+	}
+
+	return ctx.Send(utils.T(lang, "add-source-invalid"))
+}
+
+// HandleAddSticker appends a sent or forwarded sticker to the pack chosen
+// with /add.
+func HandleAddSticker(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+
+	sticker := ctx.Message().Sticker
+	if sticker == nil {
+		return ctx.Send(utils.T(lang, "add-source-invalid"))
+	}
+
+	stickers, sources := stickerSources(ctx, lang, bot, []tg.Sticker{*sticker})
+	if len(stickers) == 0 {
+		return nil
+	}
+	return appendStickers(ctx, lang, stickers, sources, bot, sessions, repo)
+}
+
+// stickerSources fetches the sets that single stickers come from. Stickers
+// whose set no longer exists are skipped, since the pack could not be kept in
+// sync with it.
+func stickerSources(ctx tg.Context, lang string, bot *tg.Bot, stickers []tg.Sticker) ([]tg.Sticker, []types.StickerSet) {
+	var kept []tg.Sticker
+	var sources []types.StickerSet
+	fetched := make(map[string]bool)
+	missing := make(map[string]bool)
+
+	for _, sticker := range stickers {
+		name := sticker.SetName
+		if name != "" && !fetched[name] && !missing[name] {
+			stickerSet, err := services.FetchSet(bot, name)
+			if err != nil {
+				log.Printf("Error fetching pack %s to append: %v", name, err)
+				ctx.Send(utils.T(lang, "merge-set-missing", name))
+				missing[name] = true
+			} else {
+				fetched[name] = true
+				sources = append(sources, *stickerSet)
+			}
+		}
+		if !missing[name] {
+			kept = append(kept, sticker)
+		}
+	}
+
+	return kept, sources
+}
+
+// appendStickers adds the stickers of sources the target set does not hold
+// yet, as far as its capacity allows. The other items of sources are recorded
+// as left out, so syncing the pack only picks up items added to them later.
+// The user stays in the /add flow afterwards so more stickers can be sent.
+func appendStickers(ctx tg.Context, lang string, stickers []tg.Sticker, sources []types.StickerSet, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	userID := ctx.Sender().ID
+
+	session := sessions.Get(userID)
+	if session.State != services.StateWaitingForAddSource || session.TargetPack == nil {
+		return ctx.Send(utils.T(lang, "copy-in-progress"))
+	}
+	pack := session.TargetPack
+	packType := services.StickerTypeOfPack(pack.PackType)
+
+	target, err := services.FetchSet(bot, pack.PackName)
+	if err != nil {
+		log.Printf("Error fetching pack %s to append to: %v", pack.PackName, err)
+		return ctx.Send(utils.T(lang, "add-set-missing"))
+	}
+
+	copies, err := repo.GetPackItems(pack.UserID, pack.PackName)
+	if err != nil {
+		log.Printf("Error getting items of pack %s: %v", pack.PackName, err)
+		return ctx.Send(utils.T(lang, "error"))
+	}
+
+	seen := make(map[string]bool)
+	for _, sticker := range target.Stickers {
+		seen[sticker.UniqueID] = true
+	}
+	for sourceID, copyID := range copies {
+		if copyID != "" {
+			seen[sourceID] = true
+		}
+	}
+
+	var items []tg.Sticker
+	mismatched := 0
+	for _, sticker := range stickers {
+		stickerType := types.StickerType(sticker.Type)
+		if stickerType == "" {
+			stickerType = types.StickerTypeRegular
+		}
+		if stickerType != packType {
+			mismatched++
+			continue
+		}
+		if seen[sticker.UniqueID] {
+			continue
+		}
+		seen[sticker.UniqueID] = true
+		items = append(items, sticker)
+	}
+
+	if mismatched > 0 {
+		ctx.Send(utils.T(lang, "add-type-mismatch", mismatched, utils.T(lang, packTypeKey(packType))))
+	}
+
+	if len(items) == 0 {
+		return ctx.Send(utils.T(lang, "add-nothing-new"))
+	}
+
+	free := services.SetCapacity(packType) - len(target.Stickers)
+	if free <= 0 {
+		return ctx.Send(utils.T(lang, "add-pack-full", services.SetCapacity(packType)))
+	}
+	if len(items) > free {
+		ctx.Send(utils.T(lang, "add-truncated", free, len(items)-free))
+		items = items[:free]
+	}
+
+	adding := make(map[string]bool)
+	for _, sticker := range items {
+		adding[sticker.UniqueID] = true
+	}
+
+	var sourceSets, leftOut []string
+	for _, source := range sources {
+		sourceSets = append(sourceSets, source.Name)
+		for _, sticker := range source.Stickers {
+			if !adding[sticker.UniqueID] {
+				leftOut = append(leftOut, sticker.UniqueID)
+			}
+		}
+	}
+
+	sourceSets = services.AppendSourceSets(pack.SourceSets, sourceSets)
+	job, err := services.NewAppendJob(repo, lang, pack, len(target.Stickers), services.CopyItemsFromStickers(items), sourceSets, leftOut)
+	if err != nil {
+		log.Printf("Error creating append job: %v", err)
+		return ctx.Send(utils.T(lang, "error"))
+	}
+
+	result, err := runCopyJob(bot, ctx.Recipient(), job, utils.T(lang, "adding-to-pack", len(items), pack.PackTitle), sessions, repo)
+	reportCopyOutcome(bot, ctx.Recipient(), job, result, err)
+
+	if updated, err := repo.GetPackByID(pack.ID, userID); err == nil && updated != nil {
+		current := *sessions.Get(userID)
+		if current.State == services.StateWaitingForAddSource {
+			current.TargetPack = updated
+			sessions.Set(userID, &current)
+		}
+	}
+
+	return nil
+}
@@ -137,5 +137,16 @@ func HandleAdminStats(ctx tg.Context, repo *db.Repository) error {
 		time.Now().Format("2006-01-02 15:04:05"),
 	)
 
+	strategyStats, err := repo.GetStrategyStats()
+	if err != nil {
+		log.Printf("Failed to get strategy stats: %v", err)
+	}
+	if len(strategyStats) > 0 {
+		stats += "\n\n📦 *Copied stickers*"
+		for _, stat := range strategyStats {
+			stats += fmt.Sprintf("\n%s: `%d` (avg `%.0f ms`)", stat.Strategy, stat.Count, stat.AvgDurationMs)
+		}
+	}
+
 	return ctx.Send(stats, &tg.SendOptions{ParseMode: tg.ModeMarkdown})
 }
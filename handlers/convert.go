@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+var BtnConvertPack = tg.Btn{Unique: "convert_pack"}
+
+// HandleConvertPack switches the type of the offered copy between a sticker
+// pack and a custom emoji pack. Items are resized for the chosen type while
+// they are copied.
+func HandleConvertPack(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Sender().LanguageCode
+	userID := ctx.Sender().ID
+	session := *sessions.Get(userID)
+
+	if session.State != services.StateWaitingForPackName && session.State != services.StateWaitingForFallbackEmoji {
+		return ctx.Respond()
+	}
+
+	target := conversionTarget(&session)
+	if target == "" {
+		return ctx.Respond()
+	}
+
+	session.PackType = target
+	sessions.Set(userID, &session)
+
+	ctx.Bot().EditReplyMarkup(ctx.Message(), itemsMarkup(lang, &session))
+	return ctx.Respond(&tg.CallbackResponse{Text: utils.T(lang, "convert-target", utils.T(lang, packTypeKey(target)))})
+}
+
+// conversionTarget returns the type the session's copy can be switched to,
+// or an empty type when its items can't be converted. Only sticker and
+// custom emoji packs convert into each other.
+func conversionTarget(session *services.Session) types.StickerType {
+	switch session.PackType {
+	case types.StickerTypeRegular:
+		return types.StickerTypeEmoji
+	case types.StickerTypeEmoji:
+		return types.StickerTypeRegular
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+var BtnCreateDone = tg.Btn{Unique: "create_done"}
+
+// HandleCreateStart starts collecting the images of a new sticker pack.
+func HandleCreateStart(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	if sessions.Get(userID).State == services.StateCopying {
+		return ctx.Send(utils.T(lang, "copy-in-progress"))
+	}
+
+	sessions.Set(userID, &services.Session{
+		State:    services.StateCollectingImages,
+		PackType: types.StickerTypeRegular,
+	})
+	return ctx.Send(utils.T(lang, "create-start", services.SetCapacity(types.StickerTypeRegular)))
+}
+
+// HandleCreateImage turns a sent photo or image document into a sticker of
+// the pack being created. An emoji sent as the caption becomes its emoji.
+func HandleCreateImage(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+	message := ctx.Message()
+
+	var file tg.File
+	switch {
+	case message.Photo != nil:
+		file = message.Photo.File
+	case message.Document != nil && services.ImageMIMETypes[message.Document.MIME]:
+		file = message.Document.File
+	default:
+		return ctx.Send(utils.T(lang, "create-unsupported"))
+	}
+
+	capacity := services.SetCapacity(types.StickerTypeRegular)
+	if len(sessions.Get(userID).OriginalItems) >= capacity {
+		return ctx.Send(utils.T(lang, "create-full", capacity))
+	}
+
+	emoji := ""
+	if caption := strings.TrimSpace(message.Caption); utils.IsEmoji(caption) {
+		emoji = caption
+	}
+
+	sticker, err := services.StickerFromImage(context.Background(), bot, ctx.Sender(), file, emoji)
+	if err != nil {
+		log.Printf("Error turning image of user %d into a sticker: %v", userID, err)
+		return ctx.Send(utils.T(lang, "create-image-failed"))
+	}
+
+	// Images of an album arrive together, so the session is updated in place
+	session := sessions.Update(userID, func(session *services.Session) {
+		if session.State == services.StateCollectingImages && len(session.OriginalItems) < capacity {
+			session.OriginalItems = append(session.OriginalItems, *sticker)
+		}
+	})
+	if session.State != services.StateCollectingImages {
+		return nil
+	}
+
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(utils.T(lang, "btn-create-done"), BtnCreateDone.Unique)))
+
+	if emoji != "" {
+		return ctx.Send(utils.T(lang, "create-image-added-emoji", len(session.OriginalItems), emoji), markup)
+	}
+	return ctx.Send(utils.T(lang, "create-image-added", len(session.OriginalItems)), markup)
+}
+
+// HandleCreateText gives the last added image the emoji sent by the user.
+func HandleCreateText(ctx tg.Context, text string, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	if !utils.IsEmoji(text) || len(sessions.Get(userID).OriginalItems) == 0 {
+		return ctx.Send(utils.T(lang, "create-send-image"))
+	}
+
+	emoji := strings.TrimSpace(text)
+	session := sessions.Update(userID, func(session *services.Session) {
+		if n := len(session.OriginalItems); n > 0 {
+			items := append([]tg.Sticker(nil), session.OriginalItems...)
+			items[n-1].Emoji = emoji
+			session.OriginalItems = items
+		}
+	})
+
+	return ctx.Send(utils.T(lang, "create-emoji-set", emoji, len(session.OriginalItems)))
+}
+
+// HandleCreateDone shows the collected images and asks for the details of
+// the new pack, the same way as for a copied pack.
+func HandleCreateDone(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Sender().LanguageCode
+	session := sessions.Get(ctx.Sender().ID)
+
+	if session.State != services.StateCollectingImages {
+		return ctx.Respond()
+	}
+
+	if len(session.OriginalItems) == 0 {
+		return ctx.Respond(&tg.CallbackResponse{Text: utils.T(lang, "create-need-image")})
+	}
+
+	ctx.Respond()
+	ctx.Bot().EditReplyMarkup(ctx.Message(), nil)
+
+	return offerItems(ctx, lang, &services.Session{
+		Title:         utils.T(lang, "create-title"),
+		OriginalItems: session.OriginalItems,
+		PackType:      types.StickerTypeRegular,
+	}, sessions)
+}
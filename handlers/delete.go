@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+var (
+	BtnDeleteFromList   = tg.Btn{Unique: "delete_from_list"}
+	BtnDeleteOnTelegram = tg.Btn{Unique: "delete_on_telegram"}
+	BtnConfirmDelete    = tg.Btn{Unique: "confirm_delete"}
+	BtnCancelDelete     = tg.Btn{Unique: "cancel_delete"}
+)
+
+// HandleDeletePack asks whether to only drop a pack from the list or to
+// delete its set on Telegram as well.
+func HandleDeletePack(ctx tg.Context, packID int64, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	pack, err := repo.GetPackByID(packID, userID)
+	if err != nil {
+		log.Printf("Error getting pack %d for user %d: %v", packID, userID, err)
+		return ctx.Send(utils.T(lang, "error"))
+	}
+	if pack == nil {
+		return ctx.Send(utils.T(lang, "delete-not-found"))
+	}
+
+	data := strconv.FormatInt(pack.ID, 10)
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(markup.Data(utils.T(lang, "btn-delete-from-list"), BtnDeleteFromList.Unique, data)),
+		markup.Row(markup.Data(utils.T(lang, "btn-delete-on-telegram"), BtnDeleteOnTelegram.Unique, data)),
+		markup.Row(markup.Data(utils.T(lang, "btn-cancel-delete"), BtnCancelDelete.Unique)),
+	)
+
+	return ctx.Send(utils.T(lang, "delete-choose", pack.PackTitle, pack.PackLink), markup)
+}
+
+// HandleDeleteFromList drops a pack from the list and leaves its set alive.
+func HandleDeleteFromList(ctx tg.Context, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+
+	pack, err := callbackPack(ctx, repo)
+	if err != nil {
+		log.Printf("Error loading pack for user %d: %v", ctx.Sender().ID, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+	if pack == nil {
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "delete-not-found"))
+	}
+
+	if err := deletePack(repo, pack, false); err != nil {
+		log.Printf("Error deleting pack %d for user %d: %v", pack.ID, pack.UserID, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+
+	ctx.Respond()
+	return ctx.Edit(utils.T(lang, "delete-removed-from-list", pack.PackTitle))
+}
+
+// HandleDeleteOnTelegram asks to confirm deleting a pack's set on Telegram.
+func HandleDeleteOnTelegram(ctx tg.Context, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+
+	pack, err := callbackPack(ctx, repo)
+	if err != nil {
+		log.Printf("Error loading pack for user %d: %v", ctx.Sender().ID, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+	if pack == nil {
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "delete-not-found"))
+	}
+
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(utils.T(lang, "btn-confirm-delete"), BtnConfirmDelete.Unique, strconv.FormatInt(pack.ID, 10)),
+		markup.Data(utils.T(lang, "btn-cancel-delete"), BtnCancelDelete.Unique),
+	))
+
+	ctx.Respond()
+	return ctx.Edit(utils.T(lang, "delete-confirm", pack.PackTitle), markup)
+}
+
+// HandleConfirmDelete deletes a pack's set on Telegram and drops the pack
+// from the list. A set that is already gone counts as deleted.
+func HandleConfirmDelete(ctx tg.Context, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+
+	pack, err := callbackPack(ctx, repo)
+	if err != nil {
+		log.Printf("Error loading pack for user %d: %v", ctx.Sender().ID, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+	if pack == nil {
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "delete-not-found"))
+	}
+
+	if err := ctx.Bot().DeleteStickerSet(pack.PackName); err != nil && !isSetMissingError(err) {
+		log.Printf("Failed to delete set %s: %v", pack.PackName, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "delete-set-failed"))
+	}
+
+	if err := deletePack(repo, pack, true); err != nil {
+		log.Printf("Error deleting pack %d for user %d: %v", pack.ID, pack.UserID, err)
+	}
+
+	log.Printf("Set %s of user %d deleted on Telegram", pack.PackName, pack.UserID)
+	ctx.Respond()
+	return ctx.Edit(utils.T(lang, "delete-set-deleted", pack.PackTitle))
+}
+
+func HandleCancelDelete(ctx tg.Context) error {
+	lang := ctx.Sender().LanguageCode
+	ctx.Respond()
+	return ctx.Edit(utils.T(lang, "delete-cancelled"))
+}
+
+// deletePack drops pack from the list and records how it was deleted.
+func deletePack(repo *db.Repository, pack *db.Pack, onTelegram bool) error {
+	if err := repo.DeletePack(pack.ID, pack.UserID); err != nil {
+		return err
+	}
+	recordPackDeletion(repo, pack.UserID, pack.PackName, pack.PackTitle, onTelegram)
+	return nil
+}
+
+func recordPackDeletion(repo *db.Repository, userID int64, packName, packTitle string, onTelegram bool) {
+	deletion := &db.PackDeletion{
+		UserID:            userID,
+		PackName:          packName,
+		PackTitle:         packTitle,
+		DeletedOnTelegram: onTelegram,
+	}
+	if err := repo.RecordPackDeletion(deletion); err != nil {
+		log.Printf("Failed to record deletion of %s: %v", packName, err)
+	}
+}
+
+func callbackPack(ctx tg.Context, repo *db.Repository) (*db.Pack, error) {
+	packID, err := strconv.ParseInt(ctx.Data(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pack id %q: %w", ctx.Data(), err)
+	}
+	return repo.GetPackByID(packID, ctx.Sender().ID)
+}
+
+func isSetMissingError(err error) bool {
+	errStr := err.Error()
+	return strings.Contains(errStr, "STICKERSET_INVALID") || strings.Contains(errStr, "not found")
+}
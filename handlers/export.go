@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"strconv"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// HandleExport sends the items of a pack as ZIP archives with a manifest.
+// The pack is named by a link or by the id of one of the user's packs.
+func HandleExport(ctx tg.Context, arg string, bot *tg.Bot, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	var packName string
+	if packID, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		pack, err := repo.GetPackByID(packID, userID)
+		if err != nil || pack == nil {
+			log.Printf("Error getting pack %d for user %d: %v", packID, userID, err)
+			return ctx.Send(utils.T(lang, "export-not-found"))
+		}
+		packName = pack.PackName
+	} else if packNames := utils.ExtractPackNames(arg); len(packNames) > 0 {
+		packName = packNames[0]
+	} else {
+		return ctx.Send(utils.T(lang, "export-usage"))
+	}
+
+	stickerSet, err := services.FetchSet(bot, packName)
+	if err != nil {
+		log.Printf("Error fetching pack %s to export: %v", packName, err)
+		if utils.IsBotError(err, "pack-not-found") {
+			return ctx.Send(utils.T(lang, "pack-not-found"))
+		}
+		return ctx.Send(utils.T(lang, "error"))
+	}
+
+	ctx.Send(utils.T(lang, "export-started", stickerSet.Title, len(stickerSet.Stickers)))
+
+	archives, skipped, err := services.ExportSet(context.Background(), bot, stickerSet)
+	if err != nil {
+		log.Printf("Error exporting pack %s for user %d: %v", packName, userID, err)
+		return ctx.Send(utils.T(lang, "export-failed"))
+	}
+	defer services.RemoveArchives(archives)
+
+	for i, archive := range archives {
+		document := &tg.Document{
+			File:     tg.FromDisk(archive),
+			FileName: filepath.Base(archive),
+		}
+		if len(archives) > 1 {
+			document.Caption = utils.T(lang, "export-part", i+1, len(archives))
+		}
+
+		if _, err := bot.Send(ctx.Recipient(), document); err != nil {
+			log.Printf("Failed to send archive %s to user %d: %v", archive, userID, err)
+			return ctx.Send(utils.T(lang, "export-failed"))
+		}
+	}
+
+	if skipped > 0 {
+		return ctx.Send(utils.T(lang, "export-skipped", skipped))
+	}
+	return nil
+}
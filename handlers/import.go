@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// maxDownloadBytes is the largest file the Bot API lets a bot download.
+const maxDownloadBytes = 20 * 1024 * 1024
+
+// HandleImportArchive uploads the stickers of a sent ZIP archive, reports
+// the entries that were skipped and asks for the details of the new pack, the
+// same way as for a copied pack.
+func HandleImportArchive(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+	document := ctx.Message().Document
+
+	if document.FileSize > maxDownloadBytes {
+		return ctx.Send(utils.T(lang, "import-too-large", maxDownloadBytes/1024/1024))
+	}
+
+	// The import holds the session like a copy does, so /cancel can stop it
+	// and no other copy or import starts meanwhile
+	importCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var previous services.Session
+	sessions.Update(userID, func(session *services.Session) {
+		previous = *session
+		if session.State != services.StateCopying {
+			session.State = services.StateCopying
+			session.Cancel = cancel
+		}
+	})
+	if previous.State == services.StateCopying {
+		return ctx.Send(utils.T(lang, "copy-in-progress"))
+	}
+
+	result, err := importArchive(importCtx, ctx, lang, bot, document)
+	if previous.State == services.StateIdle {
+		sessions.Clear(userID)
+	} else {
+		sessions.Set(userID, &previous)
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return ctx.Send(utils.T(lang, "import-cancelled"))
+		}
+		if utils.IsBotError(err, "import-too-many") {
+			return ctx.Send(utils.T(lang, "import-too-many", services.SetCapacity(types.StickerTypeEmoji)))
+		}
+		return ctx.Send(utils.T(lang, "import-failed"))
+	}
+
+	message := utils.T(lang, "import-summary", len(result.Items), result.Total)
+	for i, skipped := range result.Skipped {
+		if i == maxListedFailures {
+			message += utils.T(lang, "failed-more", len(result.Skipped)-i)
+			break
+		}
+		message += utils.T(lang, "import-skipped-item", skipped.File, utils.T(lang, "reason-"+string(skipped.Reason)))
+	}
+	ctx.Send(message)
+
+	if len(result.Items) == 0 {
+		return ctx.Send(utils.T(lang, "import-empty"))
+	}
+
+	stickers := make([]tg.Sticker, len(result.Items))
+	imported := make(map[string]types.CopyItem, len(result.Items))
+	for i, item := range result.Items {
+		stickers[i] = item.Sticker
+		imported[item.UniqueID] = item
+	}
+
+	title := result.Title
+	if title == "" {
+		title = utils.T(lang, "import-title")
+	}
+
+	return offerItems(ctx, lang, &services.Session{
+		Title:         title,
+		OriginalItems: stickers,
+		PackType:      result.Type,
+		ImportedItems: imported,
+	}, sessions)
+}
+
+// importArchive downloads the sent archive and imports its stickers until
+// importCtx is cancelled.
+func importArchive(importCtx context.Context, ctx tg.Context, lang string, bot *tg.Bot, document *tg.Document) (*services.ImportResult, error) {
+	userID := ctx.Sender().ID
+
+	ctx.Send(utils.T(lang, "import-started"))
+
+	archivePath, err := services.DownloadFile(bot, document.File)
+	if err != nil {
+		log.Printf("Error downloading archive of user %d: %v", userID, err)
+		return nil, err
+	}
+	defer utils.CleanupFiles([]string{archivePath})
+
+	result, err := services.ImportArchive(importCtx, bot, ctx.Sender(), archivePath)
+	if err != nil {
+		log.Printf("Error importing archive of user %d: %v", userID, err)
+		return nil, err
+	}
+	return result, nil
+}
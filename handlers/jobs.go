@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+var (
+	BtnStopCopy          = tg.Btn{Unique: "stop_copy"}
+	BtnDeletePartialPack = tg.Btn{Unique: "delete_partial"}
+	BtnKeepPartialPack   = tg.Btn{Unique: "keep_partial"}
+	BtnRetryFailed       = tg.Btn{Unique: "retry_failed"}
+)
+
+// maxListedFailures caps the failed items listed in a copy report.
+const maxListedFailures = 20
+
+// ResumeJobs continues every job left unfinished by a previous run and
+// notifies its owner that the copy was resumed. The remaining parts of a split
+// copy are resumed together and reported like a new split copy.
+func ResumeJobs(bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) {
+	jobs, err := repo.GetUnfinishedJobs()
+	if err != nil {
+		log.Printf("Failed to load unfinished jobs: %v", err)
+		return
+	}
+
+	if len(jobs) > 0 {
+		log.Printf("Resuming %d unfinished job(s)", len(jobs))
+	}
+
+	type groupKey struct {
+		userID int64
+		name   string
+	}
+	var groups [][]*db.Job
+	groupIndex := make(map[groupKey]int)
+	for i := range jobs {
+		job := &jobs[i]
+		if job.GroupName == "" {
+			groups = append(groups, []*db.Job{job})
+			continue
+		}
+
+		key := groupKey{job.UserID, job.GroupName}
+		if index, ok := groupIndex[key]; ok {
+			groups[index] = append(groups[index], job)
+			continue
+		}
+		groupIndex[key] = len(groups)
+		groups = append(groups, []*db.Job{job})
+	}
+
+	for _, group := range groups {
+		job := group[0]
+		lang := job.LanguageCode
+		recipient := &tg.User{ID: job.UserID}
+		typeKey := packTypeKey(types.StickerType(job.StickerType))
+
+		log.Printf("Resuming job %d for user %d at %d/%d", job.ID, job.UserID, job.AddedCount, job.TotalCount)
+
+		progressText := utils.T(lang, "copy-resumed", utils.T(lang, typeKey), job.SetTitle, job.AddedCount, job.TotalCount)
+		if job.GroupName == "" {
+			result, err := runCopyJob(bot, recipient, job, progressText, sessions, repo)
+			reportCopyOutcome(bot, recipient, job, result, err)
+			continue
+		}
+
+		bot.Send(recipient, progressText)
+		err := runJobGroup(bot, recipient, finishedParts(repo, group), group, sessions, repo)
+		if utils.IsBotError(err, "name-taken") && job.AddedCount == 0 {
+			reportCopyOutcome(bot, recipient, job, nil, err)
+		}
+	}
+}
+
+// finishedParts returns the packs of the split copy that jobs belong to which
+// were completed before jobs.
+func finishedParts(repo *db.Repository, jobs []*db.Job) []db.Pack {
+	packs, err := repo.GetGroupPacks(jobs[0].UserID, jobs[0].GroupName)
+	if err != nil {
+		log.Printf("Failed to load the parts of %s: %v", jobs[0].GroupName, err)
+		return nil
+	}
+
+	unfinished := make(map[string]bool)
+	for _, job := range jobs {
+		unfinished[job.SetName] = true
+	}
+
+	var finished []db.Pack
+	for _, pack := range packs {
+		if !unfinished[pack.PackName] {
+			finished = append(finished, pack)
+		}
+	}
+	return finished
+}
+
+// runCopyJob runs job while the owner's session holds its cancel function,
+// showing a progress message with a Stop button unless progressText is empty.
+// The previous session is restored once the job returns.
+func runCopyJob(bot *tg.Bot, recipient tg.Recipient, job *db.Job, progressText string, sessions *services.SessionStore, repo *db.Repository) (*types.CopyResult, error) {
+	lang := job.LanguageCode
+
+	copyCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	previous := sessions.Get(job.UserID)
+	copying := *previous
+	copying.State = services.StateCopying
+	copying.Cancel = cancel
+	sessions.Set(job.UserID, &copying)
+	defer func() {
+		if previous.State == services.StateIdle {
+			sessions.Clear(job.UserID)
+		} else {
+			sessions.Set(job.UserID, previous)
+		}
+	}()
+
+	stopMarkup := &tg.ReplyMarkup{}
+	stopMarkup.Inline(stopMarkup.Row(stopMarkup.Data(utils.T(lang, "btn-stop"), BtnStopCopy.Unique)))
+
+	var progressMsg *tg.Message
+	if progressText != "" {
+		msg, err := bot.Send(recipient, progressText, stopMarkup)
+		if err != nil {
+			log.Printf("Failed to send progress message: %v", err)
+		}
+		progressMsg = msg
+	}
+
+	progressCallback := func(current, total int) {
+		if progressMsg != nil {
+			newText := utils.T(lang, "copy-progress", current, total)
+			_, err := bot.Edit(progressMsg, newText, stopMarkup)
+			if err != nil {
+				log.Printf("Failed to update progress: %v", err)
+			}
+		}
+	}
+
+	result, err := services.RunCopyJob(copyCtx, bot, repo, job, progressCallback)
+
+	if progressMsg != nil {
+		bot.Delete(progressMsg)
+	}
+
+	return result, err
+}
+
+// runJobGroup runs the jobs of a copy split over several sets one after
+// another and sends the links of all sets once they are done. finished holds
+// the parts completed earlier, when a split copy is resumed. The first job
+// that does not complete stops the group and the remaining jobs are
+// cancelled. Its outcome is reported unless it is the first job failing on a
+// taken name, which is left to the caller.
+func runJobGroup(bot *tg.Bot, recipient tg.Recipient, finished []db.Pack, jobs []*db.Job, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := jobs[0].LanguageCode
+	typeName := utils.T(lang, packTypeKey(types.StickerType(jobs[0].StickerType)))
+	parts := len(finished) + len(jobs)
+
+	var links string
+	for _, pack := range finished {
+		links += utils.T(lang, "split-link", pack.PackTitle, pack.PackLink)
+	}
+
+	for i, job := range jobs {
+		progressText := utils.T(lang, "creating-split-pack", len(finished)+i+1, parts, typeName, job.SetTitle)
+
+		result, err := runCopyJob(bot, recipient, job, progressText, sessions, repo)
+		if err != nil {
+			cancelJobs(repo, jobs[i+1:])
+			if i > 0 || !utils.IsBotError(err, "name-taken") {
+				reportCopyOutcome(bot, recipient, job, result, err)
+			}
+			return err
+		}
+
+		if len(result.Failed) > 0 {
+			sendCopyResult(bot, recipient, job, result)
+		}
+		links += utils.T(lang, "split-link", job.SetTitle, result.PackLink)
+	}
+
+	bot.Send(recipient, utils.T(lang, "split-success", typeName, parts, links))
+	return nil
+}
+
+// cancelJobs marks jobs that will not run anymore as cancelled.
+func cancelJobs(repo *db.Repository, jobs []*db.Job) {
+	for _, job := range jobs {
+		if err := repo.UpdateJobStatus(job.ID, db.JobStatusCancelled); err != nil {
+			log.Printf("Failed to cancel job %d: %v", job.ID, err)
+		}
+	}
+}
+
+// reportCopyOutcome tells the owner how a copy job ended.
+func reportCopyOutcome(bot *tg.Bot, recipient tg.Recipient, job *db.Job, result *types.CopyResult, err error) {
+	if err != nil {
+		if utils.IsBotError(err, "copy-cancelled") {
+			sendCopyCancelled(bot, recipient, job)
+			return
+		}
+		if utils.IsBotError(err, "name-taken") {
+			bot.Send(recipient, utils.T(job.LanguageCode, "auto-name-taken", job.SetTitle, job.SetName))
+			return
+		}
+		log.Printf("Error running job %d: %v", job.ID, err)
+		bot.Send(recipient, utils.T(job.LanguageCode, "error"))
+		return
+	}
+
+	sendCopyResult(bot, recipient, job, result)
+}
+
+// sendCopyResult reports a finished copy, listing skipped items and offering
+// to retry them.
+func sendCopyResult(bot *tg.Bot, recipient tg.Recipient, job *db.Job, result *types.CopyResult) {
+	lang := job.LanguageCode
+	typeName := utils.T(lang, packTypeKey(types.StickerType(job.StickerType)))
+
+	if len(result.Failed) == 0 {
+		bot.Send(recipient, utils.T(lang, "success", typeName, result.PackLink))
+		return
+	}
+
+	message := utils.T(lang, "success-partial", typeName, result.Added, result.Total, len(result.Failed), result.PackLink)
+	for i, item := range result.Failed {
+		if i == maxListedFailures {
+			message += utils.T(lang, "failed-more", len(result.Failed)-i)
+			break
+		}
+		message += utils.T(lang, "failed-item", item.Position+1, item.Emoji, utils.T(lang, "reason-"+string(item.Reason)))
+	}
+
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(utils.T(lang, "btn-retry-failed"), BtnRetryFailed.Unique, strconv.FormatInt(job.ID, 10))))
+
+	bot.Send(recipient, message, markup)
+}
+
+// sendCopyCancelled reports how far a cancelled copy got and, when a partial
+// set exists, offers to delete it. Sets that existed before the job are never
+// offered for deletion.
+func sendCopyCancelled(bot *tg.Bot, recipient tg.Recipient, job *db.Job) {
+	lang := job.LanguageCode
+
+	if job.AddedCount == 0 {
+		bot.Send(recipient, utils.T(lang, "copy-cancelled-empty"))
+		return
+	}
+
+	if job.BaseCount > 0 {
+		packLink := services.PackLink(job.SetName, types.StickerType(job.StickerType))
+		bot.Send(recipient, utils.T(lang, "add-cancelled", job.AddedCount, job.TotalCount, packLink))
+		return
+	}
+
+	jobID := strconv.FormatInt(job.ID, 10)
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(utils.T(lang, "btn-delete-partial"), BtnDeletePartialPack.Unique, jobID),
+		markup.Data(utils.T(lang, "btn-keep-partial"), BtnKeepPartialPack.Unique, jobID),
+	))
+
+	packLink := services.PackLink(job.SetName, types.StickerType(job.StickerType))
+	bot.Send(recipient, utils.T(lang, "copy-cancelled", job.AddedCount, job.TotalCount, packLink), markup)
+}
+
+func HandleStopCopy(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Sender().LanguageCode
+	session := sessions.Get(ctx.Sender().ID)
+
+	if session.Cancel == nil {
+		return ctx.Respond()
+	}
+
+	session.Cancel()
+	return ctx.Respond(&tg.CallbackResponse{Text: utils.T(lang, "cancel-requested")})
+}
+
+func HandleDeletePartialPack(ctx tg.Context, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+	userID := ctx.Sender().ID
+
+	job, err := callbackJob(ctx, repo)
+	if err != nil || job == nil || job.BaseCount > 0 {
+		log.Printf("Error loading job for user %d: %v", userID, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+
+	// A set that is already gone still leaves its records to clean up
+	if err := ctx.Bot().DeleteStickerSet(job.SetName); err != nil && !isSetMissingError(err) {
+		log.Printf("Failed to delete partial set %s: %v", job.SetName, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+
+	if err := repo.DeletePackByName(job.SetName, userID); err != nil {
+		log.Printf("Failed to delete partial pack %s from database: %v", job.SetName, err)
+	}
+	if err := repo.ClearFailedJobItems(job.ID); err != nil {
+		log.Printf("Failed to clear failed items of job %d: %v", job.ID, err)
+	}
+	recordPackDeletion(repo, userID, job.SetName, job.SetTitle, true)
+
+	ctx.Respond()
+	return ctx.Edit(utils.T(lang, "partial-deleted"))
+}
+
+func HandleKeepPartialPack(ctx tg.Context, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+
+	job, err := callbackJob(ctx, repo)
+	if err != nil || job == nil {
+		log.Printf("Error loading job for user %d: %v", ctx.Sender().ID, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+
+	packLink := services.PackLink(job.SetName, types.StickerType(job.StickerType))
+	ctx.Respond()
+	return ctx.Edit(utils.T(lang, "partial-kept", job.AddedCount, packLink))
+}
+
+func HandleRetryFailed(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+	userID := ctx.Sender().ID
+
+	if sessions.Get(userID).State == services.StateCopying {
+		return ctx.Respond(&tg.CallbackResponse{Text: utils.T(lang, "copy-in-progress")})
+	}
+
+	job, err := callbackJob(ctx, repo)
+	if err != nil || job == nil {
+		log.Printf("Error loading job for user %d: %v", userID, err)
+		ctx.Respond()
+		return ctx.Edit(utils.T(lang, "error"))
+	}
+
+	failedItems, err := repo.GetFailedJobItems(job.ID)
+	if err != nil {
+		log.Printf("Error loading failed items of job %d: %v", job.ID, err)
+		ctx.Respond()
+		return ctx.Send(utils.T(lang, "error"))
+	}
+
+	if err := repo.ClearFailedJobItems(job.ID); err != nil {
+		log.Printf("Error clearing failed items of job %d: %v", job.ID, err)
+		ctx.Respond()
+		return ctx.Send(utils.T(lang, "error"))
+	}
+
+	ctx.Respond()
+	bot.EditReplyMarkup(ctx.Message(), nil)
+
+	result, err := runCopyJob(bot, ctx.Sender(), job, utils.T(lang, "retrying-failed", len(failedItems)), sessions, repo)
+	reportCopyOutcome(bot, ctx.Sender(), job, result, err)
+	return nil
+}
+
+func callbackJob(ctx tg.Context, repo *db.Repository) (*db.Job, error) {
+	jobID, err := strconv.ParseInt(ctx.Data(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job id %q: %w", ctx.Data(), err)
+	}
+	return repo.GetJobByID(jobID, ctx.Sender().ID)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+var BtnMergeDone = tg.Btn{Unique: "merge_done"}
+
+// HandleMergeStart starts collecting the packs to merge into one.
+func HandleMergeStart(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	if sessions.Get(userID).State == services.StateCopying {
+		return ctx.Send(utils.T(lang, "copy-in-progress"))
+	}
+
+	sessions.Set(userID, &services.Session{State: services.StateCollectingMergeSets})
+	return ctx.Send(utils.T(lang, "merge-start"))
+}
+
+// HandleMergeLinks adds the named packs to the merge being collected. Every
+// pack must have the same type as the first one.
+func HandleMergeLinks(ctx tg.Context, packNames []string, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+	session := *sessions.Get(userID)
+
+	for _, packName := range packNames {
+		if mergeHasSet(session.MergeSets, packName) {
+			continue
+		}
+
+		stickerSet, err := services.FetchSet(bot, packName)
+		if err != nil {
+			log.Printf("Error fetching pack %s to merge: %v", packName, err)
+			ctx.Send(utils.T(lang, "merge-set-missing", packName))
+			continue
+		}
+
+		if len(session.MergeSets) > 0 && stickerSet.Type != session.MergeSets[0].Type {
+			ctx.Send(utils.T(lang, "merge-type-mismatch", stickerSet.Title, utils.T(lang, packTypeKey(stickerSet.Type)), utils.T(lang, packTypeKey(session.MergeSets[0].Type))))
+			continue
+		}
+
+		session.MergeSets = append(session.MergeSets, *stickerSet)
+	}
+
+	sessions.Set(userID, &session)
+
+	if len(session.MergeSets) == 0 {
+		return nil
+	}
+
+	stickers, _ := services.MergeSets(session.MergeSets)
+
+	message := utils.T(lang, "merge-progress", len(session.MergeSets), len(stickers))
+	for i, set := range session.MergeSets {
+		message += utils.T(lang, "queue-item", i+1, set.Title, utils.T(lang, packTypeKey(set.Type)), len(set.Stickers))
+	}
+
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(utils.T(lang, "btn-merge-done"), BtnMergeDone.Unique)))
+
+	return ctx.Send(message, markup)
+}
+
+// HandleMergeSticker adds the pack of a sent sticker to the merge being
+// collected.
+func HandleMergeSticker(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+
+	sticker := ctx.Message().Sticker
+	if sticker == nil || sticker.SetName == "" {
+		return ctx.Send(utils.T(lang, "sticker-no-set"))
+	}
+
+	return HandleMergeLinks(ctx, []string{sticker.SetName}, bot, sessions)
+}
+
+// HandleMergeText adds the packs linked in a message, or the pack of its
+// first custom emoji, to the merge being collected.
+func HandleMergeText(ctx tg.Context, text string, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+
+	if packNames := utils.ExtractPackNames(text); len(packNames) > 0 {
+		return HandleMergeLinks(ctx, packNames, bot, sessions)
+	}
+
+	if ids := utils.CustomEmojiIDs(ctx.Message()); len(ids) > 0 {
+		setName, err := services.FetchCustomEmojiSetName(bot, ids)
+		if err != nil {
+			log.Printf("Error fetching custom emoji %v: %v", ids, err)
+			return ctx.Send(utils.T(lang, "error"))
+		}
+		if setName == "" {
+			return ctx.Send(utils.T(lang, "sticker-no-set"))
+		}
+		return HandleMergeLinks(ctx, []string{setName}, bot, sessions)
+	}
+
+	return ctx.Send(utils.T(lang, "merge-start"))
+}
+
+// HandleMergeDone combines the collected packs without duplicates and asks
+// for the name of the merged copy. Merges beyond the capacity of one set are
+// split over several sets when copied.
+func HandleMergeDone(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Sender().LanguageCode
+	session := sessions.Get(ctx.Sender().ID)
+
+	if session.State != services.StateCollectingMergeSets {
+		return ctx.Respond()
+	}
+
+	if len(session.MergeSets) < 2 {
+		return ctx.Respond(&tg.CallbackResponse{Text: utils.T(lang, "merge-need-more")})
+	}
+
+	ctx.Respond()
+	ctx.Bot().EditReplyMarkup(ctx.Message(), nil)
+
+	sets := session.MergeSets
+	packType := sets[0].Type
+	stickers, duplicates := services.MergeSets(sets)
+
+	message := utils.T(lang, "merge-summary", len(sets), len(stickers), duplicates)
+	if capacity := services.SetCapacity(packType); len(stickers) > capacity {
+		message += utils.T(lang, "merge-split", capacity, (len(stickers)+capacity-1)/capacity)
+	}
+	ctx.Send(message)
+
+	sourceSets := make([]string, len(sets))
+	for i, set := range sets {
+		sourceSets[i] = set.Name
+	}
+
+	return offerItems(ctx, lang, &services.Session{
+		Title:         services.MergedTitle(sets),
+		OriginalItems: stickers,
+		PackType:      packType,
+		SourceSets:    sourceSets,
+	}, sessions)
+}
+
+func mergeHasSet(sets []types.StickerSet, name string) bool {
+	for _, set := range sets {
+		if strings.EqualFold(set.Name, name) {
+			return true
+		}
+	}
+	return false
+}
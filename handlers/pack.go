@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"fmt"
 	"log"
 	"sort"
+	"strings"
 	"tg-sticker-stiller-bot/db"
 	"tg-sticker-stiller-bot/services"
 	"tg-sticker-stiller-bot/types"
@@ -12,112 +12,334 @@ import (
 	tg "gopkg.in/telebot.v4"
 )
 
-func HandlePack(ctx tg.Context, packName string, packType types.StickerType, bot *tg.Bot, sessions *services.SessionStore) error {
+var (
+	BtnDefaultFallbackEmoji = tg.Btn{Unique: "default_fallback_emoji"}
+	BtnAcceptSlug           = tg.Btn{Unique: "accept_slug"}
+)
+
+func HandlePack(ctx tg.Context, packName string, bot *tg.Bot, sessions *services.SessionStore) error {
 	lang := ctx.Message().Sender.LanguageCode
+
+	stickerSet, err := services.FetchSet(bot, packName)
+	if err != nil {
+		log.Printf("Error fetching pack %s: %v", packName, err)
+		if utils.IsBotError(err, "pack-not-found") {
+			return ctx.Send(utils.T(lang, "pack-not-found"))
+		}
+		return ctx.Send(utils.T(lang, "error"))
+	}
+
+	return offerPack(ctx, lang, stickerSet, nil, sessions)
+}
+
+// offerPack shows the stats of a fetched set and asks for the details of its
+// copy. queue holds the sets still waiting to be copied after this one.
+func offerPack(ctx tg.Context, lang string, stickerSet *types.StickerSet, queue []types.StickerSet, sessions *services.SessionStore) error {
+	return offerItems(ctx, lang, &services.Session{
+		Title:         stickerSet.Title,
+		OriginalItems: stickerSet.Stickers,
+		Name:          stickerSet.Name,
+		PackType:      stickerSet.Type,
+		SourceSets:    []string{stickerSet.Name},
+		Queue:         queue,
+	}, sessions)
+}
+
+// offerItems shows the stats of the items held by session and asks for a
+// fallback emoji when some have none, or for the name of the copy otherwise.
+func offerItems(ctx tg.Context, lang string, session *services.Session, sessions *services.SessionStore) error {
 	userID := ctx.Sender().ID
+	packType := session.PackType
 
-	var stickerSet *types.StickerSet
-	var err error
+	session.State = services.StateWaitingForPackName
+	session.FallbackEmoji = services.DefaultFallbackEmoji
 
-	if packType == types.StickerTypeEmoji {
-		emojiSet, fetchErr := services.FetchEmojiSet(bot, packName)
-		if fetchErr != nil {
-			log.Printf("Error fetching emoji pack %s: %v", packName, fetchErr)
-			return ctx.Send(utils.T(lang, "error"))
-		}
-		stickerSet = &types.StickerSet{
-			Name:     emojiSet.Name,
-			Title:    emojiSet.Title,
-			Stickers: emojiSet.Stickers,
-		}
-	} else {
-		stickerSet, err = services.FetchStickerSet(bot, packName)
-		if err != nil {
-			log.Printf("Error fetching sticker pack %s: %v", packName, err)
-			return ctx.Send(utils.T(lang, "error"))
+	missingEmoji := 0
+	for _, sticker := range session.OriginalItems {
+		if sticker.Emoji == "" {
+			missingEmoji++
 		}
 	}
 
-	packTypeKey := "pack-type"
-	if packType == types.StickerTypeEmoji {
-		packTypeKey = "emoji-type"
+	if session.SourceType == "" {
+		session.SourceType = packType
 	}
 
-	ctx.Send(utils.T(lang, "pack-stats", utils.T(lang, packTypeKey), stickerSet.Title, len(stickerSet.Stickers)))
+	if missingEmoji > 0 {
+		session.State = services.StateWaitingForFallbackEmoji
+		sessions.Set(userID, session)
 
-	sessions.Set(userID, &services.Session{
-		State:         services.StateWaitingForPackName,
-		Title:         stickerSet.Title,
-		OriginalItems: stickerSet.Stickers,
-		Name:          packName,
-		PackType:      packType,
-	})
+		return ctx.Send(utils.T(lang, "pack-stats-missing-emoji", utils.T(lang, packTypeKey(session.SourceType)), session.Title, len(session.OriginalItems), missingEmoji), itemsMarkup(lang, session))
+	}
+
+	ctx.Send(utils.T(lang, "pack-stats", utils.T(lang, packTypeKey(session.SourceType)), session.Title, len(session.OriginalItems)), itemsMarkup(lang, session))
+	sessions.Set(userID, session)
 
 	return nil
 }
 
-func HandlePackNameInput(ctx tg.Context, userInput string, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+// itemsMarkup builds the buttons shown with the stats of the session's items:
+// the default fallback emoji when one is asked for, picking items and
+// converting the copy to another set type.
+func itemsMarkup(lang string, session *services.Session) *tg.ReplyMarkup {
+	markup := &tg.ReplyMarkup{}
+
+	var rows []tg.Row
+	if session.State == services.StateWaitingForFallbackEmoji {
+		rows = append(rows, markup.Row(markup.Data(utils.T(lang, "btn-default-fallback-emoji", services.DefaultFallbackEmoji), BtnDefaultFallbackEmoji.Unique)))
+	}
+	rows = append(rows, markup.Row(markup.Data(utils.T(lang, "btn-select-items"), BtnSelectItems.Unique)))
+	if target := conversionTarget(session); target != "" {
+		rows = append(rows, markup.Row(markup.Data(utils.T(lang, "btn-convert", utils.T(lang, packTypeKey(target))), BtnConvertPack.Unique)))
+	}
+
+	markup.Inline(rows...)
+	return markup
+}
+
+// HandleSticker starts copying the set a sent or forwarded sticker belongs to.
+func HandleSticker(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+
+	if sessions.Get(ctx.Sender().ID).State == services.StateCopying {
+		return ctx.Send(utils.T(lang, "copy-in-progress"))
+	}
+
+	sticker := ctx.Message().Sticker
+	if sticker == nil || sticker.SetName == "" {
+		return ctx.Send(utils.T(lang, "sticker-no-set"))
+	}
+
+	return HandlePack(ctx, sticker.SetName, bot, sessions)
+}
+
+// HandleCustomEmoji starts copying the set of the first custom emoji in a
+// message.
+func HandleCustomEmoji(ctx tg.Context, customEmojiIDs []string, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+
+	setName, err := services.FetchCustomEmojiSetName(bot, customEmojiIDs)
+	if err != nil {
+		log.Printf("Error fetching custom emoji %v: %v", customEmojiIDs, err)
+		return ctx.Send(utils.T(lang, "error"))
+	}
+
+	if setName == "" {
+		return ctx.Send(utils.T(lang, "sticker-no-set"))
+	}
+
+	return HandlePack(ctx, setName, bot, sessions)
+}
+
+// HandleFallbackEmojiInput stores the emoji given to items that have none and
+// moves on to naming the pack.
+func HandleFallbackEmojiInput(ctx tg.Context, userInput string, sessions *services.SessionStore) error {
 	lang := ctx.Message().Sender.LanguageCode
+
+	if !utils.IsEmoji(userInput) {
+		return ctx.Send(utils.T(lang, "fallback-emoji-invalid"))
+	}
+
+	return setFallbackEmoji(ctx, lang, strings.TrimSpace(userInput), sessions)
+}
+
+func HandleDefaultFallbackEmoji(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Sender().LanguageCode
+
+	if sessions.Get(ctx.Sender().ID).State != services.StateWaitingForFallbackEmoji {
+		return ctx.Respond()
+	}
+
+	ctx.Respond()
+	return setFallbackEmoji(ctx, lang, services.DefaultFallbackEmoji, sessions)
+}
+
+func setFallbackEmoji(ctx tg.Context, lang, emoji string, sessions *services.SessionStore) error {
 	userID := ctx.Sender().ID
+	session := *sessions.Get(userID)
 
-	session := sessions.Get(userID)
+	session.FallbackEmoji = emoji
+	session.State = services.StateWaitingForPackName
+	sessions.Set(userID, &session)
+
+	return ctx.Send(utils.T(lang, "ask-pack-name", utils.T(lang, packTypeKey(session.PackType)), session.Title))
+}
+
+// HandleTitleInput takes the title of the copy, which may use any script,
+// and proposes a URL name derived from it that the user can accept or
+// replace.
+func HandleTitleInput(ctx tg.Context, userInput string, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+	session := *sessions.Get(userID)
 
 	if len(session.OriginalItems) == 0 {
 		sessions.Clear(userID)
 		return ctx.Send(utils.T(lang, "no-pack-data"))
 	}
 
-	normalizedName := utils.NormalizePackName(userInput)
-
-	if !utils.ValidateNormalizedName(normalizedName) {
-		errKey := utils.GetValidationError(normalizedName)
+	title := strings.TrimSpace(userInput)
+	if errKey := utils.GetTitleValidationError(title); errKey != "" {
 		return ctx.Send(utils.T(lang, errKey))
 	}
 
-	packTypeKey := "pack-type"
-	if session.PackType == types.StickerTypeEmoji {
-		packTypeKey = "emoji-type"
+	session.CopyTitle = title
+	session.ProposedSlug = utils.AutoPackName(title, session.Name, bot.Me.Username)
+	session.State = services.StateWaitingForSlug
+
+	if session.ProposedSlug != "" {
+		session.ProposedSlug = freePackName(bot, session.ProposedSlug, copyParts(&session))
 	}
+	sessions.Set(userID, &session)
 
-	progressMsg, err := ctx.Bot().Send(ctx.Recipient(), utils.T(lang, "creating-pack", utils.T(lang, packTypeKey)))
-	if err != nil {
-		log.Printf("Failed to send progress message: %v", err)
+	if !utils.ValidateNormalizedName(session.ProposedSlug, bot.Me.Username) {
+		return ctx.Send(utils.T(lang, "ask-slug", title))
 	}
 
-	progressCallback := func(current, total int) {
-		if progressMsg != nil {
-			newText := fmt.Sprintf("📦 Processing: %d/%d items...", current, total)
-			_, err := ctx.Bot().Edit(progressMsg, newText)
-			if err != nil {
-				log.Printf("Failed to update progress: %v", err)
-			}
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(utils.T(lang, "btn-accept-slug", session.ProposedSlug), BtnAcceptSlug.Unique)))
+
+	setName := utils.GenerateSetName(session.ProposedSlug, bot.Me.Username)
+	return ctx.Send(utils.T(lang, "slug-proposal", title, services.PackLink(setName, session.PackType)), markup)
+}
+
+// HandleSlugInput copies the pack under the URL name typed by the user.
+func HandleSlugInput(ctx tg.Context, userInput string, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+
+	slug := utils.NormalizePackName(userInput)
+	if !utils.ValidateNormalizedName(slug, bot.Me.Username) {
+		errKey := utils.GetValidationError(slug, bot.Me.Username)
+		if errKey == "name-too-long" {
+			return ctx.Send(utils.T(lang, errKey, utils.MaxPackNameLength(bot.Me.Username)))
 		}
+		return ctx.Send(utils.T(lang, errKey))
+	}
+
+	return startCopy(ctx, lang, slug, bot, sessions, repo)
+}
+
+// HandleAcceptSlug copies the pack under the proposed URL name.
+func HandleAcceptSlug(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+	session := sessions.Get(ctx.Sender().ID)
+
+	if session.State != services.StateWaitingForSlug || session.ProposedSlug == "" {
+		return ctx.Respond()
 	}
 
-	packLink, err := services.CreateStickerSet(bot, userID, bot.Me.Username, userInput, session.OriginalItems, session.PackType, repo, progressCallback)
+	ctx.Respond()
+	bot.EditReplyMarkup(ctx.Message(), nil)
+
+	return startCopy(ctx, lang, session.ProposedSlug, bot, sessions, repo)
+}
+
+// startCopy copies the session's items into a set named after slug and moves
+// on to the next queued pack. When the name is taken the user is asked for
+// another one.
+func startCopy(ctx tg.Context, lang, slug string, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	userID := ctx.Sender().ID
+	session := sessions.Get(userID)
+
+	if len(session.OriginalItems) == 0 {
+		sessions.Clear(userID)
+		return ctx.Send(utils.T(lang, "no-pack-data"))
+	}
+
+	typeKey := packTypeKey(session.PackType)
+
+	taken, err := services.IsPackNameTaken(bot, slug, copyParts(session))
+	if err != nil {
+		log.Printf("Error checking pack name %s: %v", slug, err)
+	} else if taken {
+		return suggestPackName(ctx, lang, slug, bot, sessions)
+	}
+
+	items := sessionItems(session)
+	jobs, err := services.NewCopyJobGroup(repo, userID, lang, slug, bot.Me.Username, session.CopyTitle, items, session.PackType, session.FallbackEmoji, session.SourceSets, session.LeftOut)
 	if err != nil {
-		if progressMsg != nil {
-			ctx.Bot().Delete(progressMsg)
-		}
-		if botErr, ok := err.(*utils.BotError); ok {
-			if botErr.I18nKey == "name-taken" {
-				return ctx.Send(utils.T(lang, "name-taken"))
-			}
-		}
 		sessions.Clear(userID)
-		log.Printf("Error creating sticker set: %v", err)
+		log.Printf("Error creating copy job: %v", err)
 		return ctx.Send(utils.T(lang, "error"))
 	}
 
-	if progressMsg != nil {
-		ctx.Bot().Delete(progressMsg)
+	var result *types.CopyResult
+	if len(jobs) == 1 {
+		result, err = runCopyJob(bot, ctx.Recipient(), jobs[0], utils.T(lang, "creating-pack", utils.T(lang, typeKey)), sessions, repo)
+	} else {
+		err = runJobGroup(bot, ctx.Recipient(), nil, jobs, sessions, repo)
+	}
+	if utils.IsBotError(err, "name-taken") && jobs[0].AddedCount == 0 {
+		return suggestPackName(ctx, lang, slug, bot, sessions)
 	}
 
-	ctx.Send(utils.T(lang, "success", utils.T(lang, packTypeKey), packLink))
+	queue := session.Queue
 	sessions.Clear(userID)
+	if len(jobs) == 1 {
+		reportCopyOutcome(bot, ctx.Recipient(), jobs[0], result, err)
+	}
+
+	if len(queue) > 0 && !utils.IsBotError(err, "copy-cancelled") {
+		return offerPack(ctx, lang, &queue[0], queue[1:], sessions)
+	}
 	return nil
 }
 
+// suggestPackName tells the user that slug is taken and offers the first free
+// name derived from it as a one-tap button.
+func suggestPackName(ctx tg.Context, lang, slug string, bot *tg.Bot, sessions *services.SessionStore) error {
+	userID := ctx.Sender().ID
+	session := *sessions.Get(userID)
+
+	session.ProposedSlug = freePackName(bot, slug, copyParts(&session))
+	if session.ProposedSlug == slug {
+		session.ProposedSlug = ""
+	}
+	session.State = services.StateWaitingForSlug
+	sessions.Set(userID, &session)
+
+	if session.ProposedSlug == "" {
+		return ctx.Send(utils.T(lang, "name-taken"))
+	}
+
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(utils.T(lang, "btn-accept-slug", session.ProposedSlug), BtnAcceptSlug.Unique)))
+
+	setName := utils.GenerateSetName(session.ProposedSlug, bot.Me.Username)
+	return ctx.Send(utils.T(lang, "name-taken-suggest", services.PackLink(setName, session.PackType)), markup)
+}
+
+// freePackName returns the first free name derived from slug, or slug itself
+// when Telegram can't be asked or every candidate is taken.
+func freePackName(bot *tg.Bot, slug string, parts int) string {
+	if slug == "" {
+		return slug
+	}
+
+	free := services.FindFreePackName(bot, slug, parts)
+	if free == "" {
+		return slug
+	}
+	return free
+}
+
+// copyParts returns how many sets the session's items are split over.
+func copyParts(session *services.Session) int {
+	return len(services.SplitItems(sessionItems(session), session.PackType))
+}
+
+// sessionItems wraps the session's items for copying, keeping the emojis,
+// keywords and mask positions known for items imported from an archive.
+func sessionItems(session *services.Session) []types.CopyItem {
+	items := services.CopyItemsFromStickers(session.OriginalItems)
+	for i := range items {
+		if imported, ok := session.ImportedItems[items[i].UniqueID]; ok {
+			items[i] = imported
+		}
+	}
+	return items
+}
+
 func HandleListPacks(ctx tg.Context, repo *db.Repository) error {
 	lang := ctx.Message().Sender.LanguageCode
 	userID := ctx.Sender().ID
@@ -144,15 +366,13 @@ func HandleListPacks(ctx tg.Context, repo *db.Repository) error {
 	return ctx.Send(message)
 }
 
-func HandleDeletePack(ctx tg.Context, packID int64, repo *db.Repository) error {
-	lang := ctx.Message().Sender.LanguageCode
-	userID := ctx.Sender().ID
-
-	err := repo.DeletePack(packID, userID)
-	if err != nil {
-		log.Printf("Error deleting pack %d for user %d: %v", packID, userID, err)
-		return ctx.Send(utils.T(lang, "delete-not-found"))
+func packTypeKey(packType types.StickerType) string {
+	switch packType {
+	case types.StickerTypeEmoji:
+		return "emoji-type"
+	case types.StickerTypeMask:
+		return "mask-type"
+	default:
+		return "pack-type"
 	}
-
-	return ctx.Send(utils.T(lang, "delete-success"))
 }
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"log"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+var (
+	BtnQueueNameEach = tg.Btn{Unique: "queue_name_each"}
+	BtnQueueAutoName = tg.Btn{Unique: "queue_auto_name"}
+)
+
+// maxQueuedPacks caps how many links of a single message are queued.
+const maxQueuedPacks = 10
+
+// HandlePackLinks fetches every pack linked in a message, shows a summary and
+// asks whether to name each copy or to generate the names.
+func HandlePackLinks(ctx tg.Context, packNames []string, bot *tg.Bot, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	if len(packNames) > maxQueuedPacks {
+		ctx.Send(utils.T(lang, "queue-too-many", maxQueuedPacks))
+		packNames = packNames[:maxQueuedPacks]
+	}
+
+	var queue []types.StickerSet
+	var summary string
+	for _, packName := range packNames {
+		stickerSet, err := services.FetchSet(bot, packName)
+		if err != nil {
+			log.Printf("Error fetching queued pack %s: %v", packName, err)
+			summary += utils.T(lang, "queue-item-missing", packName)
+			continue
+		}
+
+		queue = append(queue, *stickerSet)
+		summary += utils.T(lang, "queue-item", len(queue), stickerSet.Title, utils.T(lang, packTypeKey(stickerSet.Type)), len(stickerSet.Stickers))
+	}
+
+	if len(queue) == 0 {
+		return ctx.Send(utils.T(lang, "pack-not-found"))
+	}
+
+	if len(queue) == 1 {
+		ctx.Send(summary)
+		return offerPack(ctx, lang, &queue[0], nil, sessions)
+	}
+
+	sessions.Set(userID, &services.Session{
+		State: services.StateWaitingForQueueChoice,
+		Queue: queue,
+	})
+
+	markup := &tg.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(markup.Data(utils.T(lang, "btn-queue-name-each"), BtnQueueNameEach.Unique)),
+		markup.Row(markup.Data(utils.T(lang, "btn-queue-auto-name"), BtnQueueAutoName.Unique)),
+	)
+
+	return ctx.Send(utils.T(lang, "queue-summary", len(queue), summary), markup)
+}
+
+// HandleQueueNameEach walks through the queued packs one naming prompt at a
+// time.
+func HandleQueueNameEach(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Sender().LanguageCode
+	session := sessions.Get(ctx.Sender().ID)
+
+	if session.State != services.StateWaitingForQueueChoice || len(session.Queue) == 0 {
+		return ctx.Respond()
+	}
+
+	ctx.Respond()
+	ctx.Bot().EditReplyMarkup(ctx.Message(), nil)
+
+	queue := session.Queue
+	return offerPack(ctx, lang, &queue[0], queue[1:], sessions)
+}
+
+// HandleQueueAutoName copies every queued pack under a name generated from
+// its source. All jobs are persisted up front so a restart resumes the rest
+// of the queue.
+func HandleQueueAutoName(ctx tg.Context, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Sender().LanguageCode
+	userID := ctx.Sender().ID
+	session := sessions.Get(userID)
+
+	if session.State != services.StateWaitingForQueueChoice || len(session.Queue) == 0 {
+		return ctx.Respond()
+	}
+
+	queue := session.Queue
+	sessions.Clear(userID)
+
+	ctx.Respond()
+	bot.EditReplyMarkup(ctx.Message(), nil)
+
+	var jobs []*db.Job
+	for _, stickerSet := range queue {
+		items := services.CopyItemsFromStickers(stickerSet.Stickers)
+		slug := freePackName(bot, utils.AutoPackName(stickerSet.Title, stickerSet.Name, bot.Me.Username), 1)
+		setName := utils.GenerateSetName(slug, bot.Me.Username)
+
+		job, err := services.NewCopyJob(repo, userID, lang, setName, stickerSet.Title, items, stickerSet.Type, services.DefaultFallbackEmoji, []string{stickerSet.Name}, nil)
+		if err != nil {
+			log.Printf("Error creating copy job for %s: %v", stickerSet.Name, err)
+			ctx.Send(utils.T(lang, "error"))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	for i, job := range jobs {
+		typeName := utils.T(lang, packTypeKey(types.StickerType(job.StickerType)))
+		progressText := utils.T(lang, "creating-queued-pack", i+1, len(jobs), typeName, job.SetTitle)
+
+		result, err := runCopyJob(bot, ctx.Sender(), job, progressText, sessions, repo)
+		reportCopyOutcome(bot, ctx.Sender(), job, result, err)
+
+		if utils.IsBotError(err, "copy-cancelled") {
+			cancelJobs(repo, jobs[i+1:])
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// HandleRenamePack changes the title of one of the user's packs on Telegram
+// and in the list.
+func HandleRenamePack(ctx tg.Context, packID int64, title string, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+	title = strings.TrimSpace(title)
+
+	if errKey := utils.GetTitleValidationError(title); errKey != "" {
+		return ctx.Send(utils.T(lang, errKey))
+	}
+
+	pack, err := repo.GetPackByID(packID, userID)
+	if err != nil || pack == nil {
+		log.Printf("Error getting pack %d for user %d: %v", packID, userID, err)
+		return ctx.Send(utils.T(lang, "rename-not-found"))
+	}
+
+	if err := ctx.Bot().SetStickerSetTitle(tg.StickerSet{Name: pack.PackName, Title: title}); err != nil {
+		log.Printf("Failed to rename set %s: %v", pack.PackName, err)
+		return ctx.Send(utils.T(lang, "rename-failed"))
+	}
+
+	if err := repo.UpdatePackTitle(pack.ID, userID, title); err != nil {
+		log.Printf("Failed to save title of pack %d: %v", pack.ID, err)
+	}
+
+	return ctx.Send(utils.T(lang, "rename-success", pack.PackTitle, title, pack.PackLink))
+}
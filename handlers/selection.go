@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"log"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+var BtnSelectItems = tg.Btn{Unique: "select_items"}
+
+// HandleSelectItems asks which items of the offered pack to copy.
+func HandleSelectItems(ctx tg.Context, sessions *services.SessionStore) error {
+	lang := ctx.Sender().LanguageCode
+	userID := ctx.Sender().ID
+	session := *sessions.Get(userID)
+
+	if session.State != services.StateWaitingForPackName && session.State != services.StateWaitingForFallbackEmoji {
+		return ctx.Respond()
+	}
+
+	ctx.Respond()
+	ctx.Bot().EditReplyMarkup(ctx.Message(), nil)
+
+	session.State = services.StateWaitingForSelection
+	sessions.Set(userID, &session)
+
+	return ctx.Send(utils.T(lang, "select-items", len(session.OriginalItems)))
+}
+
+// HandleSelectionInput keeps only the items named by numbers and ranges such
+// as "1-10,15,20-25", in their original order, and offers the rest again.
+func HandleSelectionInput(ctx tg.Context, userInput string, sessions *services.SessionStore) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+	session := *sessions.Get(userID)
+
+	positions, err := utils.ParseSelection(userInput, len(session.OriginalItems))
+	if err != nil {
+		log.Printf("Invalid selection %q from user %d: %v", userInput, userID, err)
+		return ctx.Send(utils.T(lang, "selection-invalid", len(session.OriginalItems)))
+	}
+
+	selected := make([]tg.Sticker, len(positions))
+	chosen := make(map[int]bool)
+	for i, position := range positions {
+		selected[i] = session.OriginalItems[position]
+		chosen[position] = true
+	}
+
+	// Deselected items are remembered so that syncing the copy skips them
+	leftOut := append([]string(nil), session.LeftOut...)
+	for position, sticker := range session.OriginalItems {
+		if !chosen[position] {
+			leftOut = append(leftOut, sticker.UniqueID)
+		}
+	}
+	session.OriginalItems = selected
+	session.LeftOut = leftOut
+
+	ctx.Send(utils.T(lang, "selection-applied", len(selected)))
+	return offerItems(ctx, lang, &session, sessions)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/services"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+	"time"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// HandleSyncPack brings one of the user's packs in line with its sources.
+func HandleSyncPack(ctx tg.Context, packID int64, bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository) error {
+	lang := ctx.Message().Sender.LanguageCode
+	userID := ctx.Sender().ID
+
+	if sessions.Get(userID).State == services.StateCopying {
+		return ctx.Send(utils.T(lang, "copy-in-progress"))
+	}
+
+	pack, err := repo.GetPackByID(packID, userID)
+	if err != nil {
+		log.Printf("Error getting pack %d for user %d: %v", packID, userID, err)
+		return ctx.Send(utils.T(lang, "error"))
+	}
+	if pack == nil {
+		return ctx.Send(utils.T(lang, "sync-not-found"))
+	}
+	if pack.SourceSets == "" {
+		return ctx.Send(utils.T(lang, "sync-no-source"))
+	}
+
+	ctx.Send(utils.T(lang, "sync-checking", pack.PackTitle))
+
+	if err := syncPack(bot, ctx.Recipient(), lang, pack, true, sessions, repo); err != nil {
+		log.Printf("Error syncing pack %s: %v", pack.PackName, err)
+	}
+	return nil
+}
+
+// StartSyncScheduler syncs every pack that knows its sources once per
+// interval. Owners are only notified when their pack changed.
+func StartSyncScheduler(bot *tg.Bot, sessions *services.SessionStore, repo *db.Repository, interval time.Duration) {
+	log.Printf("Syncing packs with their sources every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		packs, err := repo.GetSyncablePacks()
+		if err != nil {
+			log.Printf("Failed to load packs to sync: %v", err)
+			continue
+		}
+
+		for i := range packs {
+			pack := &packs[i]
+			if sessions.Get(pack.UserID).State != services.StateIdle {
+				continue
+			}
+
+			lang, err := repo.GetUserLanguage(pack.UserID)
+			if err != nil {
+				log.Printf("Failed to get language of user %d: %v", pack.UserID, err)
+			}
+
+			if err := syncPack(bot, &tg.User{ID: pack.UserID}, lang, pack, false, sessions, repo); err != nil {
+				log.Printf("Scheduled sync of pack %s failed: %v", pack.PackName, err)
+			}
+		}
+	}
+}
+
+// syncPack appends the items added to the sources of pack since it was
+// copied and deletes the copies of items removed from them, then reports the
+// changes to the owner. Unless verbose is set, the owner only hears about
+// changes, not about failures or an unchanged pack.
+func syncPack(bot *tg.Bot, recipient tg.Recipient, lang string, pack *db.Pack, verbose bool, sessions *services.SessionStore, repo *db.Repository) error {
+	notify := func(key string, args ...interface{}) {
+		if verbose {
+			bot.Send(recipient, utils.T(lang, key, args...))
+		}
+	}
+
+	sourceSets := strings.Split(pack.SourceSets, ",")
+	var sources []types.StickerSet
+	for _, name := range sourceSets {
+		source, err := services.FetchSet(bot, name)
+		if err != nil {
+			notify("sync-source-missing", name, pack.PackTitle)
+			return fmt.Errorf("failed to fetch source %s: %w", name, err)
+		}
+		sources = append(sources, *source)
+	}
+
+	target, err := services.FetchSet(bot, pack.PackName)
+	if err != nil {
+		notify("add-set-missing")
+		return fmt.Errorf("failed to fetch pack: %w", err)
+	}
+
+	copies, err := repo.GetPackItems(pack.UserID, pack.PackName)
+	if err != nil {
+		notify("error")
+		return err
+	}
+
+	covered, err := repo.GetCoveredItems(pack.UserID, pack.PackName, pack.GroupName)
+	if err != nil {
+		notify("error")
+		return err
+	}
+
+	added, removed := services.PlanSync(sources, target, copies, covered)
+
+	removedCount := 0
+	for sourceID, sticker := range removed {
+		if err := bot.DeleteSticker(sticker.FileID); err != nil {
+			log.Printf("Failed to delete %s from %s: %v", sticker.UniqueID, pack.PackName, err)
+			continue
+		}
+		if err := repo.DeletePackItem(pack.UserID, pack.PackName, sourceID); err != nil {
+			log.Printf("Failed to forget item %s of %s: %v", sourceID, pack.PackName, err)
+		}
+		removedCount++
+	}
+
+	baseCount := len(target.Stickers) - removedCount
+	if free := services.SetCapacity(target.Type) - baseCount; len(added) > free {
+		log.Printf("Pack %s has room for %d of %d new items", pack.PackName, free, len(added))
+		added = added[:max(free, 0)]
+	}
+
+	addedCount := 0
+	if len(added) > 0 {
+		job, err := services.NewAppendJob(repo, lang, pack, baseCount, services.CopyItemsFromStickers(added), sourceSets, nil)
+		if err != nil {
+			notify("error")
+			return err
+		}
+
+		progressText := ""
+		if verbose {
+			progressText = utils.T(lang, "syncing-pack", pack.PackTitle, len(added))
+		}
+
+		result, err := runCopyJob(bot, recipient, job, progressText, sessions, repo)
+		if err != nil || len(result.Failed) > 0 {
+			reportCopyOutcome(bot, recipient, job, result, err)
+		}
+		if err != nil {
+			return err
+		}
+		addedCount = result.Added
+	} else if removedCount > 0 {
+		synced := *pack
+		synced.StickerCount = baseCount
+		if err := repo.UpsertPack(&synced); err != nil {
+			log.Printf("Failed to update pack %s after sync: %v", pack.PackName, err)
+		}
+	}
+
+	if addedCount == 0 && removedCount == 0 {
+		notify("sync-up-to-date", pack.PackTitle)
+		return nil
+	}
+
+	bot.Send(recipient, utils.T(lang, "sync-result", pack.PackTitle, addedCount, removedCount, pack.PackLink))
+	return nil
+}
@@ -2,35 +2,180 @@ package i18n
 
 var En = map[string]string{
 	"hello":   "Hello",
-	"welcome": "Welcome to Sticker & Emoji Stiller @%s!\n\nSend me one of the following:\n\nSticker pack link: t.me/addstickers/[pack_name]\nEmoji pack link: t.me/addemoji/[pack_name]\n\nI'll help you create a copy of the pack under your ownership!",
-	"help":    "Send me one of the following:\n\nSticker pack link: t.me/addstickers/[pack_name]\nEmoji pack link: t.me/addemoji/[pack_name]\n\nI'll help you create a copy of the pack under your ownership!",
+	"welcome": "Welcome to Sticker & Emoji Stiller @%s!\n\nSend me one of the following:\n\nSticker pack link: t.me/addstickers/[pack_name]\nEmoji pack link: t.me/addemoji/[pack_name]\n\nOr just send or forward any sticker or custom emoji from the pack.\n\nI'll help you create a copy of the pack under your ownership!",
+	"help":    "Send me one of the following:\n\nSticker pack link: t.me/addstickers/[pack_name]\nEmoji pack link: t.me/addemoji/[pack_name]\n\nOr just send or forward any sticker or custom emoji from the pack.\n\nUse /merge to combine several packs into one and /add <pack_id> to add more stickers to one of your packs. /sync <pack_id> picks up changes made to the pack you copied. /create makes a new pack from your own images and /export <pack link or pack_id> sends a pack as a ZIP archive. Send such an archive back to create a pack from it.\n\nI'll help you create a copy of the pack under your ownership!",
 
 	"start-command":  "Start (or restart) bot",
 	"help-command":   "Show help message",
 	"list-command":   "List your created packs",
 	"delete-command": "Delete a pack by ID",
+	"merge-command":  "Merge several packs into one",
+	"add-command":    "Add stickers to one of your packs",
+	"sync-command":   "Sync a pack with its source",
+	"create-command": "Create a sticker pack from your images",
+	"rename-command": "Rename one of your packs",
+	"export-command": "Export a pack as a ZIP archive",
 
-	"pack-stats":    "📦 Found %s pack: \"%s\"\n📊 Contains: %d items\n\nWhat would you like to name your new pack?\n\nType /cancel to cancel",
+	"pack-stats":    "📦 Found %s pack: \"%s\"\n📊 Contains: %d items\n\nSend a title for your new pack. Any language works, up to 64 characters.\n\nType /cancel to cancel",
 	"creating-pack": "Creating your %s pack... This may take a while.",
 	"success":       "✅ Success! Your %s pack is ready:\n🔗 %s",
-	"ask-pack-name": "What would you like to name your %s pack? (Original: %s)\n\nJust type a name and I'll convert it to a valid format!\n\nType /cancel to cancel",
+	"copy-resumed":  "🔄 The bot was restarted while copying your %s pack \"%s\". Your copy was resumed from item %d of %d.",
+	"ask-pack-name": "Send a title for your %s pack (Original: %s). Any language works, up to 64 characters.\n\nType /cancel to cancel",
 	"no-pack-data":  "No pack data found. Please start over.",
 	"error":         "❌ Something went wrong. Please try again later.",
-	"name-taken":    "This pack name is already taken. Please choose a different name or type /cancel to cancel.",
+	"name-taken":    "This link name is already taken. Please send a different one or type /cancel to cancel.",
+
+	"name-taken-suggest": "This link name is already taken. The closest free one is:\n🔗 %s\n\nTap the button to use it, send a different link name or type /cancel to cancel.",
+
+	"success-partial":        "✅ Your %s pack is ready: %d/%d copied, %d failed\n🔗 %s\n\nSkipped items:\n",
+	"failed-item":            "#%d %s — %s\n",
+	"failed-more":            "...and %d more\n",
+	"reason-download_failed": "download failed",
+	"reason-upload_rejected": "upload rejected",
+	"reason-format_invalid":  "format invalid",
+	"reason-set_full":        "pack is full",
+	"btn-retry-failed":       "🔁 Retry failed items",
+	"retrying-failed":        "🔁 Retrying %d failed items...",
+
+	"pack-stats-missing-emoji":   "📦 Found %s pack: \"%s\"\n📊 Contains: %d items\n\n%d items have no emoji. Send the emoji to use for them.\n\nType /cancel to cancel",
+	"btn-default-fallback-emoji": "Use %s",
+	"fallback-emoji-invalid":     "Please send a single emoji or type /cancel to cancel.",
 
 	"name-empty":         "Pack name cannot be empty. Please enter a valid name or type /cancel to cancel.",
-	"name-too-long":      "Pack name is too long (max 64 characters). Please enter a shorter name or type /cancel to cancel.",
+	"name-too-long":      "Pack name is too long (max %d characters). Please enter a shorter name or type /cancel to cancel.",
 	"name-invalid-chars": "Pack name can only contain lowercase letters (a-z), numbers (0-9), and underscores (_). Please try again or type /cancel to cancel.",
 	"cancelled":          "Operation cancelled.",
 
-	"invalid-link": "Invalid link. Please send a valid sticker or emoji pack link.",
-	"pack-type":    "sticker",
-	"emoji-type":   "emoji",
+	"btn-stop":             "⏹ Stop",
+	"copy-progress":        "📦 Processing: %d/%d items...",
+	"cancel-requested":     "⏳ Stopping the copy...",
+	"copy-in-progress":     "A pack is being copied right now. Type /cancel to stop it.",
+	"copy-cancelled":       "⏹ Copy stopped. %d of %d items were already added to the partial pack:\n🔗 %s\n\nDo you want to delete it?",
+	"copy-cancelled-empty": "⏹ Copy stopped before any items were added.",
+	"btn-delete-partial":   "🗑 Delete partial pack",
+	"btn-keep-partial":     "Keep it",
+	"partial-deleted":      "🗑 The partial pack was deleted.",
+	"partial-kept":         "✅ The partial pack with %d items was kept:\n🔗 %s",
+
+	"queue-summary":        "📚 Found %d packs:\n\n%s\nHow do you want to name the copies?",
+	"queue-item":           "%d. %s (%s) - %d items\n",
+	"queue-item-missing":   "⚠️ %s was not found and is skipped\n",
+	"queue-too-many":       "Only the first %d links are queued.",
+	"queue-choose":         "Please pick how to name the copies using the buttons above, or type /cancel to cancel.",
+	"btn-queue-name-each":  "✏️ Name each pack",
+	"btn-queue-auto-name":  "⚡ Name them automatically",
+	"creating-queued-pack": "Creating pack %d of %d: %s pack \"%s\"... This may take a while.",
+	"auto-name-taken":      "The name generated for \"%s\" (%s) is already taken, so it was skipped. Send its link again to pick a name yourself.",
+
+	"creating-split-pack": "Creating pack %d of %d: %s pack \"%s\"... This may take a while.",
+	"split-success":       "✅ Success! Your %s copy was split into %d packs:\n\n%s",
+	"split-link":          "%s\n🔗 %s\n\n",
+
+	"slug-proposal":   "Title: \"%s\"\n\nYour pack link will be:\n🔗 %s\n\nTap the button to use it, or send a different link name (letters, digits and underscores).",
+	"ask-slug":        "Title: \"%s\"\n\nNow send a link name for the pack, using Latin letters, digits and underscores.",
+	"btn-accept-slug": "✅ Use %s",
+
+	"btn-select-items":  "🎯 Pick items",
+	"select-items":      "Send the numbers of the items to copy, from 1 to %d. Ranges are allowed, for example: 1-10,15,20-25\n\nType /cancel to cancel",
+	"selection-invalid": "I couldn't read that selection. Use numbers from 1 to %d separated by commas, with ranges like 1-10,15,20-25.",
+	"selection-applied": "🎯 %d items selected.",
+
+	"btn-convert":    "🔄 Copy as %s pack",
+	"convert-target": "🔄 The copy will be a %s pack",
+
+	"merge-start":         "🔀 Send links of the packs you want to merge, or any sticker or custom emoji from them. Press Done when all packs are added, or type /cancel to cancel.",
+	"merge-progress":      "🔀 %d packs to merge, %d unique items so far:\n\n",
+	"merge-set-missing":   "⚠️ %s was not found and is skipped.",
+	"merge-type-mismatch": "⚠️ %s is a %s pack and can't be merged into %s packs, so it is skipped.",
+	"merge-need-more":     "Add at least two packs to merge.",
+	"merge-summary":       "🔀 Merged %d packs into %d unique items (%d duplicates skipped).",
+	"merge-split":         "\n📚 A pack holds at most %d items, so the copy will be split into %d packs.",
+	"btn-merge-done":      "✅ Done",
+
+	"create-start":             "🖼 Send the photos or PNG, JPEG or WebP images for your new sticker pack, up to %d. Add an emoji as the caption or send it after the image. Press Done when all images are added, or type /cancel to cancel.",
+	"create-image-added":       "🖼 Image %d added. Send an emoji for it, or the next image.",
+	"create-image-added-emoji": "🖼 Image %d added with %s.",
+	"create-emoji-set":         "%s set for image %d.",
+	"create-unsupported":       "Only photos and PNG, JPEG or WebP images can be turned into stickers.",
+	"create-image-failed":      "❌ This image could not be turned into a sticker. Please try another one.",
+	"create-full":              "A sticker pack holds at most %d items, so no more images can be added. Press Done to continue.",
+	"create-send-image":        "Send an image, an emoji for the last image, or press Done.",
+	"create-need-image":        "Add at least one image first.",
+	"create-title":             "Your images",
+	"create-hint":              "To make a sticker pack from your images, send /create first.",
+	"btn-create-done":          "✅ Done",
+
+	"export-usage":     "Usage: /export <pack link or pack_id>\n\nUse /list to see the ids of your packs.",
+	"export-not-found": "Pack not found in your list. Use /list to see your packs.",
+	"export-started":   "📦 Exporting \"%s\" (%d items)... This may take a while.",
+	"export-part":      "Part %d of %d",
+	"export-skipped":   "⚠️ %d items could not be downloaded and are missing from the archive.",
+	"export-failed":    "❌ The pack could not be exported. Please try again later.",
+
+	"import-started":      "🗜 Importing your archive... This may take a while. Send /cancel to stop.",
+	"import-summary":      "🗜 %d of %d files imported.\n",
+	"import-skipped-item": "⚠️ %s — %s\n",
+	"import-empty":        "No stickers could be imported. Send an archive with WebP, TGS or WebM files, optionally with a manifest.json.",
+	"import-too-large":    "This archive is too large. Bots can only download files up to %d MB.",
+	"import-too-many":     "This archive holds more than %d files, more than any pack can hold. Split it into several archives.",
+	"import-failed":       "❌ The archive could not be imported. Make sure it is a valid ZIP file.",
+	"import-cancelled":    "⏹ Import stopped.",
+	"import-title":        "Imported pack",
+
+	"reason-missing_file":     "file missing from the archive",
+	"reason-unsupported_type": "unsupported file type",
+	"reason-file_too_large":   "file too large",
+	"reason-extract_failed":   "file could not be extracted",
+
+	"add-usage":          "Usage: /add <pack_id>\n\nUse /list to see your packs and their IDs.",
+	"add-not-found":      "Pack not found or you don't own it.",
+	"add-set-missing":    "This pack no longer exists on Telegram.",
+	"add-pack-full":      "This pack is full: a pack holds at most %d items.",
+	"add-source":         "➕ Adding to \"%s\" (room for %d more items).\n\nSend a pack link or send/forward stickers or custom emoji. Type /cancel when you're done.",
+	"add-source-invalid": "Send a pack link, a sticker or custom emoji to add, or type /cancel when you're done.",
+	"add-type-mismatch":  "⚠️ %d items were skipped because only %s items can be added to this pack.",
+	"add-nothing-new":    "Everything you sent is already in the pack.",
+	"add-truncated":      "⚠️ Only %d more items fit in the pack, so the last %d were left out.",
+	"adding-to-pack":     "Adding %d items to \"%s\"... This may take a while.",
+	"add-cancelled":      "⏹ Adding stopped after %d of %d items:\n🔗 %s",
+
+	"sync-usage":          "Usage: /sync <pack_id>\n\nUse /list to see your packs and their IDs.",
+	"sync-not-found":      "Pack not found or you don't own it.",
+	"sync-no-source":      "This pack doesn't know which pack it was copied from, so it can't be synced.",
+	"sync-checking":       "🔄 Checking \"%s\" for changes in its source packs...",
+	"sync-source-missing": "⚠️ The source pack %s can't be fetched anymore, so \"%s\" was not synced.",
+	"sync-up-to-date":     "✅ \"%s\" is already up to date.",
+	"syncing-pack":        "🔄 Syncing \"%s\": adding %d new items...",
+	"sync-result":         "🔄 \"%s\" was synced with its source: %d items added, %d removed.\n🔗 %s",
+
+	"rename-usage":     "Usage: /rename <pack_id> <new title>\n\nUse /list to see your packs and their IDs.",
+	"rename-not-found": "Pack not found or you don't own it.",
+	"rename-failed":    "❌ Telegram couldn't change the title of this pack.",
+	"rename-success":   "✏️ \"%s\" is now called \"%s\".\n🔗 %s",
+	"title-empty":      "The title can't be empty.",
+	"title-too-long":   "The title is too long (max 64 characters).",
+
+	"invalid-link":   "Invalid link. Please send a valid sticker or emoji pack link.",
+	"pack-not-found": "This pack doesn't exist or was deleted. Please check the link.",
+	"sticker-no-set": "This sticker doesn't belong to a pack, so there is nothing to copy.",
+	"pack-type":      "sticker",
+	"emoji-type":     "emoji",
+	"mask-type":      "mask",
 
 	"list-empty":       "You haven't created any packs yet.",
 	"list-header":      "📦 Your packs:\n\n",
 	"list-item":        "%d. %s (%s) - %d items\n %s\n\n",
-	"delete-success":   "✅ Pack deleted successfully!",
 	"delete-not-found": "Pack not found or you don't have permission to delete it.",
 	"delete-usage":     "Usage: /delete <pack_id>\n\nUse /list to see your packs and their IDs.",
+
+	"delete-choose":            "🗑 What should happen to \"%s\"?\n🔗 %s",
+	"btn-delete-from-list":     "📋 Remove from my list",
+	"btn-delete-on-telegram":   "🗑 Delete on Telegram",
+	"delete-confirm":           "⚠️ This deletes \"%s\" on Telegram for everyone who added it, and it can't be undone. Are you sure?",
+	"btn-confirm-delete":       "Yes, delete it",
+	"btn-cancel-delete":        "Cancel",
+	"delete-removed-from-list": "✅ \"%s\" was removed from your list. The pack itself still exists on Telegram.",
+	"delete-set-deleted":       "🗑 \"%s\" was deleted on Telegram and removed from your list.",
+	"delete-set-failed":        "❌ Telegram couldn't delete the pack, so it was left unchanged.",
+	"delete-cancelled":         "Deletion cancelled.",
 }
@@ -2,35 +2,180 @@ package i18n
 
 var Ua = map[string]string{
 	"hello":   "Привіт",
-	"welcome": "Вітаю в Sticker & Emoji Stiller @%s!\n\nВідправте мені одне з наступного:\n\nПосилання на пакунок стікерів: t.me/addstickers/[pack_name]\nПосилання на пакунок емодзі: t.me/addemoji/[pack_name]\n\nЯ допоможу вам створити копію пакунку під вашою власністю!",
-	"help":    "Відправте мені одне з наступного:\n\nПосилання на пакунок стікерів: t.me/addstickers/[pack_name]\nПосилання на пакунок емодзі: t.me/addemoji/[pack_name]\n\nЯ допоможу вам створити копію пакунку під вашою власністю!",
+	"welcome": "Вітаю в Sticker & Emoji Stiller @%s!\n\nВідправте мені одне з наступного:\n\nПосилання на пакунок стікерів: t.me/addstickers/[pack_name]\nПосилання на пакунок емодзі: t.me/addemoji/[pack_name]\n\nАбо просто надішліть чи перешліть будь-який стікер або емодзі з пакунку.\n\nЯ допоможу вам створити копію пакунку під вашою власністю!",
+	"help":    "Відправте мені одне з наступного:\n\nПосилання на пакунок стікерів: t.me/addstickers/[pack_name]\nПосилання на пакунок емодзі: t.me/addemoji/[pack_name]\n\nАбо просто надішліть чи перешліть будь-який стікер або емодзі з пакунку.\n\nНадішліть /merge, щоб об'єднати кілька пакунків в один, та /add <pack_id>, щоб додати стікери до вашого пакунку. /sync <pack_id> підтягує зміни з пакунку, який ви скопіювали. /create створює новий пакунок з ваших зображень, а /export <посилання або pack_id> надсилає пакунок ZIP-архівом. Надішліть такий архів, щоб створити з нього пакунок.\n\nЯ допоможу вам створити копію пакунку під вашою власністю!",
 
 	"start-command":  "Запустити (або перезапустити) бота",
 	"help-command":   "Показати довідкове повідомлення",
 	"list-command":   "Показати ваші створені пакунки",
 	"delete-command": "Видалити пакунок за ID",
+	"merge-command":  "Об'єднати кілька пакунків в один",
+	"add-command":    "Додати стікери до вашого пакунку",
+	"sync-command":   "Синхронізувати пакунок з джерелом",
+	"create-command": "Створити пакунок стікерів з ваших зображень",
+	"rename-command": "Перейменувати ваш пакунок",
+	"export-command": "Експортувати пакунок у ZIP-архів",
 
-	"pack-stats":    "📦 Знайдено пакунок %s: \"%s\"\n📊 Містить: %d елементів\n\nЯк би ви хотіли назвати свій новий пакунок?\n\nНадішліть /cancel для скасування",
+	"pack-stats":    "📦 Знайдено пакунок %s: \"%s\"\n📊 Містить: %d елементів\n\nНадішліть назву для нового пакунку. Підійде будь-яка мова, до 64 символів.\n\nНадішліть /cancel для скасування",
 	"creating-pack": "Створюю ваш пакунок %s... Це може зайняти деякий час.",
 	"success":       "✅ Успіх! Ваш пакунок %s готовий:\n🔗 %s",
-	"ask-pack-name": "Як би ви хотіли назвати свій пакунок %s? (Оригінал: %s)\n\nПросто введіть назву, і я конвертую її у валідний формат!\n\nНадішліть /cancel для скасування",
+	"copy-resumed":  "🔄 Бота було перезапущено під час копіювання вашого пакунку %s \"%s\". Копіювання відновлено з елемента %d з %d.",
+	"ask-pack-name": "Надішліть назву для вашого пакунку %s (Оригінал: %s). Підійде будь-яка мова, до 64 символів.\n\nНадішліть /cancel для скасування",
 	"no-pack-data":  "Дані пакунку не знайдено. Будь ласка, почніть спочатку.",
 	"error":         "❌ Щось пішло не так. Будь ласка, спробуйте пізніше.",
-	"name-taken":    "Ця назва пакунку вже зайнята. Виберіть іншу назву або надішліть /cancel для скасування.",
+	"name-taken":    "Ця назва для посилання вже зайнята. Надішліть іншу або /cancel для скасування.",
+
+	"name-taken-suggest": "Ця назва для посилання вже зайнята. Найближча вільна:\n🔗 %s\n\nНатисніть кнопку, щоб використати її, надішліть іншу назву або /cancel для скасування.",
+
+	"success-partial":        "✅ Ваш пакунок %s готовий: скопійовано %d/%d, помилок: %d\n🔗 %s\n\nПропущені елементи:\n",
+	"failed-item":            "#%d %s — %s\n",
+	"failed-more":            "...і ще %d\n",
+	"reason-download_failed": "не вдалося завантажити",
+	"reason-upload_rejected": "Telegram відхилив файл",
+	"reason-format_invalid":  "недійсний формат",
+	"reason-set_full":        "пакунок заповнено",
+	"btn-retry-failed":       "🔁 Повторити невдалі",
+	"retrying-failed":        "🔁 Повторюю %d невдалих елементів...",
+
+	"pack-stats-missing-emoji":   "📦 Знайдено пакунок %s: \"%s\"\n📊 Містить: %d елементів\n\n%d елементів не мають емодзі. Надішліть емодзі, яке їм призначити.\n\nНадішліть /cancel для скасування",
+	"btn-default-fallback-emoji": "Використати %s",
+	"fallback-emoji-invalid":     "Будь ласка, надішліть одне емодзі або /cancel для скасування.",
 
 	"name-empty":         "Назва пакунку не може бути пустою. Введіть правильну назву або надішліть /cancel для скасування.",
-	"name-too-long":      "Назва пакунку занадто довга (максимум 64 символи). Введіть коротшу назву або надішліть /cancel для скасування.",
+	"name-too-long":      "Назва пакунку занадто довга (максимум %d символів). Введіть коротшу назву або надішліть /cancel для скасування.",
 	"name-invalid-chars": "Назва пакунку може містити тільки малі літери (a-z), цифри (0-9) та підкреслення (_). Спробуйте ще раз або надішліть /cancel для скасування.",
 	"cancelled":          "Операцію скасовано.",
 
-	"invalid-link": "Недійсне посилання. Будь ласка, надішліть дійсне посилання на пакунок стікерів або емодзі.",
-	"pack-type":    "стікерів",
-	"emoji-type":   "емодзі",
+	"btn-stop":             "⏹ Зупинити",
+	"copy-progress":        "📦 Обробка: %d/%d елементів...",
+	"cancel-requested":     "⏳ Зупиняю копіювання...",
+	"copy-in-progress":     "Зараз копіюється пакунок. Надішліть /cancel, щоб зупинити копіювання.",
+	"copy-cancelled":       "⏹ Копіювання зупинено. %d з %d елементів вже додано до неповного пакунку:\n🔗 %s\n\nВидалити його?",
+	"copy-cancelled-empty": "⏹ Копіювання зупинено до того, як було додано хоча б один елемент.",
+	"btn-delete-partial":   "🗑 Видалити неповний пакунок",
+	"btn-keep-partial":     "Залишити",
+	"partial-deleted":      "🗑 Неповний пакунок видалено.",
+	"partial-kept":         "✅ Неповний пакунок з %d елементів збережено:\n🔗 %s",
+
+	"queue-summary":        "📚 Знайдено пакунків: %d\n\n%s\nЯк назвати копії?",
+	"queue-item":           "%d. %s (%s) - %d елементів\n",
+	"queue-item-missing":   "⚠️ %s не знайдено, його пропущено\n",
+	"queue-too-many":       "У чергу додано лише перші %d посилань.",
+	"queue-choose":         "Оберіть, як назвати копії, за допомогою кнопок вище або надішліть /cancel для скасування.",
+	"btn-queue-name-each":  "✏️ Назвати кожен пакунок",
+	"btn-queue-auto-name":  "⚡ Назвати автоматично",
+	"creating-queued-pack": "Створюю пакунок %d з %d: пакунок %s \"%s\"... Це може зайняти деякий час.",
+	"auto-name-taken":      "Згенерована назва для \"%s\" (%s) вже зайнята, тому його пропущено. Надішліть посилання ще раз, щоб обрати назву самостійно.",
+
+	"creating-split-pack": "Створюю пакунок %d з %d: пакунок %s \"%s\"... Це може зайняти деякий час.",
+	"split-success":       "✅ Успіх! Вашу копію %s розділено на %d пакунків:\n\n%s",
+	"split-link":          "%s\n🔗 %s\n\n",
+
+	"slug-proposal":   "Назва: \"%s\"\n\nПосилання на ваш пакунок буде таким:\n🔗 %s\n\nНатисніть кнопку, щоб використати його, або надішліть іншу назву для посилання (літери, цифри та підкреслення).",
+	"ask-slug":        "Назва: \"%s\"\n\nТепер надішліть назву для посилання на пакунок латинськими літерами, цифрами та підкресленнями.",
+	"btn-accept-slug": "✅ Використати %s",
+
+	"btn-select-items":  "🎯 Обрати елементи",
+	"select-items":      "Надішліть номери елементів для копіювання, від 1 до %d. Можна вказувати діапазони, наприклад: 1-10,15,20-25\n\nНадішліть /cancel для скасування",
+	"selection-invalid": "Не вдалося розібрати вибір. Вкажіть номери від 1 до %d через кому, з діапазонами на кшталт 1-10,15,20-25.",
+	"selection-applied": "🎯 Обрано елементів: %d.",
+
+	"btn-convert":    "🔄 Копіювати як пакунок %s",
+	"convert-target": "🔄 Копію буде створено як пакунок %s",
+
+	"merge-start":         "🔀 Надішліть посилання на пакунки, які хочете об'єднати, або будь-який стікер чи емодзі з них. Натисніть \"Готово\", коли всі пакунки додано, або надішліть /cancel для скасування.",
+	"merge-progress":      "🔀 Пакунків для об'єднання: %d, унікальних елементів: %d:\n\n",
+	"merge-set-missing":   "⚠️ %s не знайдено, його пропущено.",
+	"merge-type-mismatch": "⚠️ %s є пакунком %s і не може бути об'єднаний з пакунками %s, тому його пропущено.",
+	"merge-need-more":     "Додайте щонайменше два пакунки для об'єднання.",
+	"merge-summary":       "🔀 Об'єднано пакунків: %d, унікальних елементів: %d (пропущено дублікатів: %d).",
+	"merge-split":         "\n📚 Пакунок може містити щонайбільше %d елементів, тому копію буде розділено на %d пакунків.",
+	"btn-merge-done":      "✅ Готово",
+
+	"create-start":             "🖼 Надішліть фото або зображення PNG, JPEG чи WebP для нового пакунку стікерів, до %d. Додайте емодзі як підпис або надішліть його після зображення. Натисніть Готово, коли всі зображення додано, або /cancel для скасування.",
+	"create-image-added":       "🖼 Зображення %d додано. Надішліть для нього емодзі або наступне зображення.",
+	"create-image-added-emoji": "🖼 Зображення %d додано з %s.",
+	"create-emoji-set":         "%s встановлено для зображення %d.",
+	"create-unsupported":       "Стікери можна зробити лише з фото та зображень PNG, JPEG чи WebP.",
+	"create-image-failed":      "❌ Це зображення не вдалося перетворити на стікер. Спробуйте інше.",
+	"create-full":              "Пакунок стікерів вміщує щонайбільше %d елементів, тому більше зображень додати не можна. Натисніть Готово, щоб продовжити.",
+	"create-send-image":        "Надішліть зображення, емодзі для останнього зображення або натисніть Готово.",
+	"create-need-image":        "Спочатку додайте хоча б одне зображення.",
+	"create-title":             "Ваші зображення",
+	"create-hint":              "Щоб створити пакунок стікерів з ваших зображень, спершу надішліть /create.",
+	"btn-create-done":          "✅ Готово",
+
+	"export-usage":     "Використання: /export <посилання на пакунок або pack_id>\n\nНадішліть /list, щоб побачити id ваших пакунків.",
+	"export-not-found": "Пакунок не знайдено у вашому списку. Надішліть /list, щоб побачити ваші пакунки.",
+	"export-started":   "📦 Експортую \"%s\" (%d елементів)... Це може зайняти деякий час.",
+	"export-part":      "Частина %d з %d",
+	"export-skipped":   "⚠️ %d елементів не вдалося завантажити, тому їх немає в архіві.",
+	"export-failed":    "❌ Не вдалося експортувати пакунок. Будь ласка, спробуйте пізніше.",
+
+	"import-started":      "🗜 Імпортую ваш архів... Це може зайняти деякий час. Надішліть /cancel, щоб зупинити.",
+	"import-summary":      "🗜 Імпортовано файлів: %d з %d.\n",
+	"import-skipped-item": "⚠️ %s — %s\n",
+	"import-empty":        "Не вдалося імпортувати жодного стікера. Надішліть архів з файлами WebP, TGS або WebM, за бажанням з manifest.json.",
+	"import-too-large":    "Цей архів завеликий. Боти можуть завантажувати файли лише до %d МБ.",
+	"import-too-many":     "Цей архів містить понад %d файлів — більше, ніж вміщує будь-який пакунок. Розділіть його на кілька архівів.",
+	"import-failed":       "❌ Не вдалося імпортувати архів. Переконайтеся, що це коректний ZIP-файл.",
+	"import-cancelled":    "⏹ Імпорт зупинено.",
+	"import-title":        "Імпортований пакунок",
+
+	"reason-missing_file":     "файлу немає в архіві",
+	"reason-unsupported_type": "непідтримуваний тип файлу",
+	"reason-file_too_large":   "файл завеликий",
+	"reason-extract_failed":   "не вдалося розпакувати файл",
+
+	"add-usage":          "Використання: /add <pack_id>\n\nВикористайте /list, щоб побачити ваші пакунки та їх ID.",
+	"add-not-found":      "Пакунок не знайдено або він вам не належить.",
+	"add-set-missing":    "Цього пакунку більше не існує в Telegram.",
+	"add-pack-full":      "Цей пакунок заповнено: пакунок може містити щонайбільше %d елементів.",
+	"add-source":         "➕ Додаю до \"%s\" (вільних місць: %d).\n\nНадішліть посилання на пакунок або надішліть/перешліть стікери чи емодзі. Надішліть /cancel, коли закінчите.",
+	"add-source-invalid": "Надішліть посилання на пакунок, стікер або емодзі для додавання чи /cancel, коли закінчите.",
+	"add-type-mismatch":  "⚠️ Пропущено елементів: %d, бо до цього пакунку можна додавати лише елементи типу %s.",
+	"add-nothing-new":    "Усе надіслане вже є в пакунку.",
+	"add-truncated":      "⚠️ До пакунку поміститься лише %d елементів, тому останні %d не увійшли.",
+	"adding-to-pack":     "Додаю %d елементів до \"%s\"... Це може зайняти деякий час.",
+	"add-cancelled":      "⏹ Додавання зупинено після %d з %d елементів:\n🔗 %s",
+
+	"sync-usage":          "Використання: /sync <pack_id>\n\nВикористайте /list, щоб побачити ваші пакунки та їх ID.",
+	"sync-not-found":      "Пакунок не знайдено або він вам не належить.",
+	"sync-no-source":      "Невідомо, з якого пакунку було скопійовано цей пакунок, тому його не можна синхронізувати.",
+	"sync-checking":       "🔄 Перевіряю зміни в джерелах \"%s\"...",
+	"sync-source-missing": "⚠️ Пакунок-джерело %s більше недоступний, тому \"%s\" не синхронізовано.",
+	"sync-up-to-date":     "✅ \"%s\" вже актуальний.",
+	"syncing-pack":        "🔄 Синхронізую \"%s\": додаю нових елементів: %d...",
+	"sync-result":         "🔄 \"%s\" синхронізовано з джерелом: додано %d, видалено %d.\n🔗 %s",
+
+	"rename-usage":     "Використання: /rename <pack_id> <нова назва>\n\nВикористайте /list, щоб побачити ваші пакунки та їх ID.",
+	"rename-not-found": "Пакунок не знайдено або він вам не належить.",
+	"rename-failed":    "❌ Telegram не вдалося змінити назву цього пакунку.",
+	"rename-success":   "✏️ \"%s\" тепер називається \"%s\".\n🔗 %s",
+	"title-empty":      "Назва не може бути порожньою.",
+	"title-too-long":   "Назва задовга (максимум 64 символи).",
+
+	"invalid-link":   "Недійсне посилання. Будь ласка, надішліть дійсне посилання на пакунок стікерів або емодзі.",
+	"pack-not-found": "Цей пакунок не існує або був видалений. Перевірте посилання.",
+	"sticker-no-set": "Цей стікер не належить до жодного пакунку, тому копіювати нічого.",
+	"pack-type":      "стікерів",
+	"emoji-type":     "емодзі",
+	"mask-type":      "масок",
 
 	"list-empty":       "Ви ще не створили жодного пакунку.",
 	"list-header":      "📦 Ваші пакунки:\n\n",
 	"list-item":        "%d. %s (%s) - %d items\n   link %s\n\n",
-	"delete-success":   "✅ Пакунок видалено успішно!",
 	"delete-not-found": "Пакунок не знайдено або у вас немає дозволу на його видалення.",
 	"delete-usage":     "Використання: /delete <pack_id>\n\nВикористайте /list щоб побачити ваші пакунки та їх ID.",
+
+	"delete-choose":            "🗑 Що зробити з \"%s\"?\n🔗 %s",
+	"btn-delete-from-list":     "📋 Прибрати зі списку",
+	"btn-delete-on-telegram":   "🗑 Видалити в Telegram",
+	"delete-confirm":           "⚠️ Пакунок \"%s\" буде видалено в Telegram для всіх, хто його додав, і це не можна скасувати. Ви впевнені?",
+	"btn-confirm-delete":       "Так, видалити",
+	"btn-cancel-delete":        "Скасувати",
+	"delete-removed-from-list": "✅ \"%s\" прибрано з вашого списку. Сам пакунок досі існує в Telegram.",
+	"delete-set-deleted":       "🗑 \"%s\" видалено в Telegram та прибрано з вашого списку.",
+	"delete-set-failed":        "❌ Telegram не вдалося видалити пакунок, тому його залишено без змін.",
+	"delete-cancelled":         "Видалення скасовано.",
 }
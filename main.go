@@ -10,7 +10,6 @@ import (
 	"tg-sticker-stiller-bot/db"
 	"tg-sticker-stiller-bot/handlers"
 	"tg-sticker-stiller-bot/services"
-	"tg-sticker-stiller-bot/types"
 	"tg-sticker-stiller-bot/utils"
 	"time"
 
@@ -103,6 +102,12 @@ func main() {
 		{Text: "/help", Description: utils.T("en", "help-command")},
 		{Text: "/list", Description: utils.T("en", "list-command")},
 		{Text: "/delete", Description: utils.T("en", "delete-command")},
+		{Text: "/merge", Description: utils.T("en", "merge-command")},
+		{Text: "/create", Description: utils.T("en", "create-command")},
+		{Text: "/add", Description: utils.T("en", "add-command")},
+		{Text: "/sync", Description: utils.T("en", "sync-command")},
+		{Text: "/rename", Description: utils.T("en", "rename-command")},
+		{Text: "/export", Description: utils.T("en", "export-command")},
 		{Text: "/cancel", Description: "Cancel current operation"},
 	})
 
@@ -137,6 +142,72 @@ func main() {
 		return handlers.HandleDeletePack(ctx, packID, repo)
 	})
 
+	bot.Handle("/merge", func(ctx tg.Context) error {
+		return handlers.HandleMergeStart(ctx, sessions)
+	})
+
+	bot.Handle("/create", func(ctx tg.Context) error {
+		return handlers.HandleCreateStart(ctx, sessions)
+	})
+
+	bot.Handle("/add", func(ctx tg.Context) error {
+		lang := ctx.Message().Sender.LanguageCode
+		args := strings.Fields(ctx.Text())
+		if len(args) < 2 {
+			return ctx.Send(utils.T(lang, "add-usage"))
+		}
+
+		packID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return ctx.Send(utils.T(lang, "add-usage"))
+		}
+
+		return handlers.HandleAddStart(ctx, packID, bot, sessions, repo)
+	})
+
+	bot.Handle("/sync", func(ctx tg.Context) error {
+		lang := ctx.Message().Sender.LanguageCode
+		args := strings.Fields(ctx.Text())
+		if len(args) < 2 {
+			return ctx.Send(utils.T(lang, "sync-usage"))
+		}
+
+		packID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return ctx.Send(utils.T(lang, "sync-usage"))
+		}
+
+		return handlers.HandleSyncPack(ctx, packID, bot, sessions, repo)
+	})
+
+	bot.Handle("/rename", func(ctx tg.Context) error {
+		lang := ctx.Message().Sender.LanguageCode
+		args := strings.Fields(ctx.Text())
+		if len(args) < 3 {
+			return ctx.Send(utils.T(lang, "rename-usage"))
+		}
+
+		packID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return ctx.Send(utils.T(lang, "rename-usage"))
+		}
+
+		title := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(ctx.Text()), args[0]))
+		title = strings.TrimSpace(strings.TrimPrefix(title, args[1]))
+
+		return handlers.HandleRenamePack(ctx, packID, title, repo)
+	})
+
+	bot.Handle("/export", func(ctx tg.Context) error {
+		lang := ctx.Message().Sender.LanguageCode
+		args := strings.Fields(ctx.Text())
+		if len(args) < 2 {
+			return ctx.Send(utils.T(lang, "export-usage"))
+		}
+
+		return handlers.HandleExport(ctx, args[1], bot, repo)
+	})
+
 	bot.Handle("/cancel", func(ctx tg.Context) error {
 		lang := ctx.Message().Sender.LanguageCode
 		userID := ctx.Sender().ID
@@ -146,6 +217,11 @@ func main() {
 			return ctx.Send(utils.T(lang, "help"))
 		}
 
+		if session.Cancel != nil {
+			session.Cancel()
+			return ctx.Send(utils.T(lang, "cancel-requested"))
+		}
+
 		sessions.Clear(userID)
 		return ctx.Send(utils.T(lang, "cancelled"))
 	})
@@ -158,6 +234,70 @@ func main() {
 		return handlers.HandleAdminStats(ctx, repo)
 	})
 
+	bot.Handle(&handlers.BtnDefaultFallbackEmoji, func(ctx tg.Context) error {
+		return handlers.HandleDefaultFallbackEmoji(ctx, sessions)
+	})
+
+	bot.Handle(&handlers.BtnAcceptSlug, func(ctx tg.Context) error {
+		return handlers.HandleAcceptSlug(ctx, bot, sessions, repo)
+	})
+
+	bot.Handle(&handlers.BtnSelectItems, func(ctx tg.Context) error {
+		return handlers.HandleSelectItems(ctx, sessions)
+	})
+
+	bot.Handle(&handlers.BtnConvertPack, func(ctx tg.Context) error {
+		return handlers.HandleConvertPack(ctx, sessions)
+	})
+
+	bot.Handle(&handlers.BtnDeleteFromList, func(ctx tg.Context) error {
+		return handlers.HandleDeleteFromList(ctx, repo)
+	})
+
+	bot.Handle(&handlers.BtnDeleteOnTelegram, func(ctx tg.Context) error {
+		return handlers.HandleDeleteOnTelegram(ctx, repo)
+	})
+
+	bot.Handle(&handlers.BtnConfirmDelete, func(ctx tg.Context) error {
+		return handlers.HandleConfirmDelete(ctx, repo)
+	})
+
+	bot.Handle(&handlers.BtnCancelDelete, func(ctx tg.Context) error {
+		return handlers.HandleCancelDelete(ctx)
+	})
+
+	bot.Handle(&handlers.BtnStopCopy, func(ctx tg.Context) error {
+		return handlers.HandleStopCopy(ctx, sessions)
+	})
+
+	bot.Handle(&handlers.BtnDeletePartialPack, func(ctx tg.Context) error {
+		return handlers.HandleDeletePartialPack(ctx, repo)
+	})
+
+	bot.Handle(&handlers.BtnKeepPartialPack, func(ctx tg.Context) error {
+		return handlers.HandleKeepPartialPack(ctx, repo)
+	})
+
+	bot.Handle(&handlers.BtnRetryFailed, func(ctx tg.Context) error {
+		return handlers.HandleRetryFailed(ctx, bot, sessions, repo)
+	})
+
+	bot.Handle(&handlers.BtnQueueNameEach, func(ctx tg.Context) error {
+		return handlers.HandleQueueNameEach(ctx, sessions)
+	})
+
+	bot.Handle(&handlers.BtnQueueAutoName, func(ctx tg.Context) error {
+		return handlers.HandleQueueAutoName(ctx, bot, sessions, repo)
+	})
+
+	bot.Handle(&handlers.BtnMergeDone, func(ctx tg.Context) error {
+		return handlers.HandleMergeDone(ctx, sessions)
+	})
+
+	bot.Handle(&handlers.BtnCreateDone, func(ctx tg.Context) error {
+		return handlers.HandleCreateDone(ctx, sessions)
+	})
+
 	bot.Handle(tg.OnText, func(ctx tg.Context) error {
 		text := ctx.Text()
 		userID := ctx.Sender().ID
@@ -167,29 +307,86 @@ func main() {
 
 		switch session.State {
 		case services.StateWaitingForPackName:
-			return handlers.HandlePackNameInput(ctx, text, bot, sessions, repo)
+			return handlers.HandleTitleInput(ctx, text, bot, sessions)
+
+		case services.StateWaitingForSlug:
+			return handlers.HandleSlugInput(ctx, text, bot, sessions, repo)
+
+		case services.StateWaitingForFallbackEmoji:
+			return handlers.HandleFallbackEmojiInput(ctx, text, sessions)
+
+		case services.StateWaitingForSelection:
+			return handlers.HandleSelectionInput(ctx, text, sessions)
+
+		case services.StateWaitingForQueueChoice:
+			return ctx.Send(utils.T(lang, "queue-choose"))
+
+		case services.StateCollectingMergeSets:
+			return handlers.HandleMergeText(ctx, text, bot, sessions)
+
+		case services.StateWaitingForAddSource:
+			return handlers.HandleAddText(ctx, text, bot, sessions, repo)
+
+		case services.StateCollectingImages:
+			return handlers.HandleCreateText(ctx, text, sessions)
+
+		case services.StateCopying:
+			return ctx.Send(utils.T(lang, "copy-in-progress"))
 
 		default:
-			if utils.IsStickerPack(text) {
-				packName := utils.ExtractStickerPackName(text)
-				if packName == "" {
-					return ctx.Send(utils.T(lang, "invalid-link"))
-				}
-				return handlers.HandlePack(ctx, packName, types.StickerTypeRegular, bot, sessions)
+			packNames := utils.ExtractPackNames(text)
+			if len(packNames) > 1 {
+				return handlers.HandlePackLinks(ctx, packNames, bot, sessions)
+			}
+			if len(packNames) == 1 {
+				return handlers.HandlePack(ctx, packNames[0], bot, sessions)
 			}
 
-			if utils.IsEmojiPack(text) {
-				packName := utils.ExtractEmojiPackName(text)
-				if packName == "" {
-					return ctx.Send(utils.T(lang, "invalid-link"))
-				}
-				return handlers.HandlePack(ctx, packName, types.StickerTypeEmoji, bot, sessions)
+			if ids := utils.CustomEmojiIDs(ctx.Message()); len(ids) > 0 {
+				return handlers.HandleCustomEmoji(ctx, ids, bot, sessions)
 			}
 
 			return ctx.Send(utils.T(lang, "invalid-link"))
 		}
 	})
 
+	bot.Handle(tg.OnSticker, func(ctx tg.Context) error {
+		switch sessions.Get(ctx.Sender().ID).State {
+		case services.StateCollectingMergeSets:
+			return handlers.HandleMergeSticker(ctx, bot, sessions)
+		case services.StateWaitingForAddSource:
+			return handlers.HandleAddSticker(ctx, bot, sessions, repo)
+		default:
+			return handlers.HandleSticker(ctx, bot, sessions)
+		}
+	})
+
+	handleImage := func(ctx tg.Context) error {
+		if sessions.Get(ctx.Sender().ID).State != services.StateCollectingImages {
+			return ctx.Send(utils.T(ctx.Message().Sender.LanguageCode, "create-hint"))
+		}
+		return handlers.HandleCreateImage(ctx, bot, sessions)
+	}
+	bot.Handle(tg.OnPhoto, handleImage)
+
+	bot.Handle(tg.OnDocument, func(ctx tg.Context) error {
+		if services.IsArchive(ctx.Message().Document) {
+			return handlers.HandleImportArchive(ctx, bot, sessions)
+		}
+		return handleImage(ctx)
+	})
+
+	go handlers.ResumeJobs(bot, sessions, repo)
+
+	if syncInterval := os.Getenv("SYNC_INTERVAL"); syncInterval != "" {
+		interval, err := time.ParseDuration(syncInterval)
+		if err != nil || interval <= 0 {
+			log.Printf("Invalid SYNC_INTERVAL %q, scheduled sync disabled", syncInterval)
+		} else {
+			go handlers.StartSyncScheduler(bot, sessions, repo, interval)
+		}
+	}
+
 	go func() {
 		log.Printf("Bot @%s started successfully\n", name)
 		if publicURL != "" {
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"tg-sticker-stiller-bot/services/media"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	"github.com/google/uuid"
+	tg "gopkg.in/telebot.v4"
+)
+
+// Bot API limits for video stickers and emoji.
+const (
+	maxVideoSeconds   = 3
+	maxVideoFrameRate = 30
+)
+
+// NeedsConversion reports whether sticker has to be resized before it can be
+// added to a set of the target type. Only custom emoji differ in size from
+// the other kinds of stickers.
+func NeedsConversion(sticker tg.Sticker, target types.StickerType) bool {
+	isEmoji := types.StickerType(sticker.Type) == types.StickerTypeEmoji
+	return isEmoji != (target == types.StickerTypeEmoji)
+}
+
+// ConvertSticker resizes the downloaded sticker at path to the dimensions of
+// the target set type and returns the path of the converted file. Static and
+// video stickers are re-encoded with ffmpeg; the canvas of animated stickers
+// is scaled in their Lottie JSON.
+func ConvertSticker(ctx context.Context, path string, sticker tg.Sticker, target types.StickerType) (string, error) {
+	size := media.StickerSize
+	if target == types.StickerTypeEmoji {
+		size = media.EmojiSize
+	}
+
+	outPath := filepath.Join(TempDir, fmt.Sprintf("%s.%s", uuid.New().String(), getFileExtension(sticker)))
+
+	var err error
+	switch {
+	case sticker.Animated:
+		err = scaleLottie(path, outPath, size)
+	case sticker.Video:
+		err = runFFmpeg(ctx, path, outPath, size,
+			"-c:v", "libvpx-vp9", "-pix_fmt", "yuva420p", "-b:v", "0", "-crf", "40",
+			"-r", fmt.Sprint(maxVideoFrameRate), "-t", fmt.Sprint(maxVideoSeconds), "-an")
+	default:
+		err = runFFmpeg(ctx, path, outPath, size, "-c:v", "libwebp", "-quality", "90", "-frames:v", "1")
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return "", err
+	}
+
+	return outPath, nil
+}
+
+// runFFmpeg scales the image or video at inPath to fit a size×size box and
+// writes it to outPath with the given encoder arguments. Custom emoji are
+// padded to an exact square with transparency.
+func runFFmpeg(ctx context.Context, inPath, outPath string, size int, encoderArgs ...string) error {
+	filter := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease:flags=lanczos", size, size)
+	if size == media.EmojiSize {
+		filter += fmt.Sprintf(",format=rgba,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=0x00000000", size, size)
+	}
+
+	args := append([]string{"-y", "-loglevel", "error", "-i", inPath, "-vf", filter}, encoderArgs...)
+	args = append(args, outPath)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// scaleLottie rewrites the TGS file at inPath with a size×size canvas. The
+// original layers are moved into a precomposition that is scaled to fit the
+// new canvas, so the animation itself is left untouched.
+func scaleLottie(inPath, outPath string, size int) error {
+	animation, err := readLottie(inPath)
+	if err != nil {
+		return err
+	}
+
+	width, _ := animation["w"].(float64)
+	height, _ := animation["h"].(float64)
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("lottie animation has no canvas size")
+	}
+
+	scale := float64(size) / max(width, height) * 100
+	assets, _ := animation["assets"].([]any)
+	animation["assets"] = append(assets, map[string]any{
+		"id":     "converted",
+		"layers": animation["layers"],
+	})
+	animation["layers"] = []any{map[string]any{
+		"ty":    0,
+		"ind":   1,
+		"refId": "converted",
+		"w":     width,
+		"h":     height,
+		"ip":    animation["ip"],
+		"op":    animation["op"],
+		"st":    0,
+		"sr":    1,
+		"ks": map[string]any{
+			"a": map[string]any{"a": 0, "k": []float64{width / 2, height / 2, 0}},
+			"p": map[string]any{"a": 0, "k": []float64{float64(size) / 2, float64(size) / 2, 0}},
+			"s": map[string]any{"a": 0, "k": []float64{scale, scale, 100}},
+			"r": map[string]any{"a": 0, "k": 0},
+			"o": map[string]any{"a": 0, "k": 100},
+		},
+	}}
+	animation["w"] = size
+	animation["h"] = size
+
+	return writeLottie(outPath, animation)
+}
+
+func readLottie(path string) (map[string]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tgs: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tgs: %w", err)
+	}
+
+	var animation map[string]any
+	if err := json.Unmarshal(data, &animation); err != nil {
+		return nil, fmt.Errorf("failed to parse lottie json: %w", err)
+	}
+	return animation, nil
+}
+
+func writeLottie(path string, animation map[string]any) error {
+	data, err := json.Marshal(animation)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.EnsureTempDir(); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write tgs: %w", err)
+	}
+	return writer.Close()
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -50,7 +51,11 @@ func DownloadFile(bot *tg.Bot, file tg.File) (string, error) {
 	return filePath, nil
 }
 
-func DownloadSticker(bot *tg.Bot, sticker tg.Sticker) (string, error) {
+func DownloadSticker(ctx context.Context, bot *tg.Bot, sticker tg.Sticker) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
 	reader, err := bot.File(&sticker.File)
 	if err != nil {
 		return "", fmt.Errorf("failed to get file: %w", err)
@@ -71,47 +76,56 @@ func DownloadSticker(bot *tg.Bot, sticker tg.Sticker) (string, error) {
 	}
 	defer file.Close()
 
-	if _, err := io.Copy(file, reader); err != nil {
+	if _, err := io.Copy(file, &contextReader{ctx: ctx, r: reader}); err != nil {
+		os.Remove(filePath)
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return filePath, nil
 }
 
-func DownloadAllStickers(bot *tg.Bot, stickers []tg.Sticker) []types.DownloadedSticker {
+// DownloadAllStickers downloads stickers concurrently. The result has one
+// entry per input sticker in the original order; entries that could not be
+// downloaded are nil.
+func DownloadAllStickers(ctx context.Context, bot *tg.Bot, stickers []tg.Sticker) []*types.DownloadedSticker {
 	var wg sync.WaitGroup
-	results := make(chan *types.DownloadedSticker, len(stickers))
+	results := make([]*types.DownloadedSticker, len(stickers))
 
-	for _, sticker := range stickers {
+	for i, sticker := range stickers {
 		wg.Add(1)
-		go func(s tg.Sticker) {
+		go func(position int, s tg.Sticker) {
 			defer wg.Done()
 
-			filePath, err := DownloadSticker(bot, s)
+			filePath, err := DownloadSticker(ctx, bot, s)
 			if err != nil {
 				log.Printf("Failed to download sticker %s, skipping: %v", s.FileID, err)
-				results <- nil
 				return
 			}
 
-			results <- &types.DownloadedSticker{
-				Path:    filePath,
-				Sticker: s,
+			results[position] = &types.DownloadedSticker{
+				Path:     filePath,
+				Position: position,
+				Sticker:  s,
 			}
-		}(sticker)
+		}(i, sticker)
 	}
 
 	wg.Wait()
-	close(results)
 
-	downloaded := []types.DownloadedSticker{}
-	for result := range results {
-		if result != nil {
-			downloaded = append(downloaded, *result)
-		}
-	}
+	return results
+}
+
+// contextReader stops a download as soon as its context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
 
-	return downloaded
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
 }
 
 func getFileExtension(item tg.Sticker) string {
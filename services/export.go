@@ -0,0 +1,194 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// ManifestFile is the name of the manifest inside an exported archive.
+const ManifestFile = "manifest.json"
+
+// maxArchiveBytes keeps exported archives below the 50 MB a bot can send,
+// leaving room for the manifest and the ZIP headers.
+const maxArchiveBytes = 48 * 1024 * 1024
+
+// Manifest describes the items of an exported pack. Packs exported into
+// several archives have one manifest per archive listing only its items.
+type Manifest struct {
+	Name  string            `json:"name"`
+	Title string            `json:"title"`
+	Type  types.StickerType `json:"type"`
+	Part  int               `json:"part,omitempty"`
+	Parts int               `json:"parts,omitempty"`
+	Items []ManifestItem    `json:"items"`
+}
+
+// ManifestItem is one file of an exported pack. Position is the place of the
+// item in the source set, counted from 0.
+type ManifestItem struct {
+	File         string           `json:"file"`
+	Position     int              `json:"position"`
+	Format       string           `json:"format"`
+	Emojis       []string         `json:"emojis"`
+	Keywords     []string         `json:"keywords"`
+	MaskPosition *tg.MaskPosition `json:"mask_position,omitempty"`
+}
+
+// ExportSet downloads every item of stickerSet and writes them into ZIP
+// archives with a manifest, starting a new archive whenever the next item
+// would push it over the upload limit. It returns the archive paths and how
+// many items could not be downloaded. The archives share one directory that
+// RemoveArchives deletes.
+func ExportSet(ctx context.Context, bot *tg.Bot, stickerSet *types.StickerSet) ([]string, int, error) {
+	downloaded := DownloadAllStickers(ctx, bot, stickerSet.Stickers)
+
+	var files []string
+	for _, sticker := range downloaded {
+		if sticker != nil {
+			files = append(files, sticker.Path)
+		}
+	}
+	defer utils.CleanupFiles(files)
+
+	items := CopyItemsFromStickers(stickerSet.Stickers)
+
+	var parts [][]*types.DownloadedSticker
+	var part []*types.DownloadedSticker
+	var partBytes int64
+	for _, sticker := range downloaded {
+		if sticker == nil {
+			continue
+		}
+
+		info, err := os.Stat(sticker.Path)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(part) > 0 && partBytes+info.Size() > maxArchiveBytes {
+			parts = append(parts, part)
+			part, partBytes = nil, 0
+		}
+		part = append(part, sticker)
+		partBytes += info.Size()
+	}
+	if len(part) > 0 {
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 0 {
+		return nil, len(stickerSet.Stickers), fmt.Errorf("no items of %s could be downloaded", stickerSet.Name)
+	}
+
+	// Archives keep the set name, so every export gets its own directory
+	dir, err := os.MkdirTemp(TempDir, "export-")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	var archives []string
+	for i, part := range parts {
+		manifest := &Manifest{
+			Name:  stickerSet.Name,
+			Title: stickerSet.Title,
+			Type:  stickerSet.Type,
+		}
+		archiveName := stickerSet.Name + ".zip"
+		if len(parts) > 1 {
+			manifest.Part, manifest.Parts = i+1, len(parts)
+			archiveName = fmt.Sprintf("%s_part%d.zip", stickerSet.Name, i+1)
+		}
+
+		archivePath := filepath.Join(dir, archiveName)
+		if err := writeArchive(archivePath, manifest, items, part); err != nil {
+			os.RemoveAll(dir)
+			return nil, 0, err
+		}
+		archives = append(archives, archivePath)
+	}
+
+	return archives, len(stickerSet.Stickers) - len(files), nil
+}
+
+// RemoveArchives deletes the archives of an export together with their
+// directory.
+func RemoveArchives(archives []string) {
+	if len(archives) == 0 {
+		return
+	}
+	if err := os.RemoveAll(filepath.Dir(archives[0])); err != nil {
+		log.Printf("Failed to delete export directory: %v", err)
+	}
+}
+
+// writeArchive stores the downloaded stickers, named after their position,
+// and the manifest describing them in a new ZIP file at path.
+func writeArchive(path string, manifest *Manifest, items []types.CopyItem, stickers []*types.DownloadedSticker) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for _, sticker := range stickers {
+		item := items[sticker.Position]
+		name := fmt.Sprintf("%03d.%s", sticker.Position+1, getFileExtension(sticker.Sticker))
+
+		// Sticker files are compressed already, so they are stored as is
+		if err := addArchiveFile(archive, name, zip.Store, sticker.Path); err != nil {
+			return err
+		}
+
+		keywords := item.Keywords
+		if keywords == nil {
+			keywords = []string{}
+		}
+		manifest.Items = append(manifest.Items, ManifestItem{
+			File:         name,
+			Position:     sticker.Position,
+			Format:       utils.GetStickerFormat(sticker.Sticker),
+			Emojis:       item.Emojis,
+			Keywords:     keywords,
+			MaskPosition: item.MaskPosition,
+		})
+	}
+
+	writer, err := archive.Create(ManifestFile)
+	if err != nil {
+		return fmt.Errorf("failed to add manifest: %w", err)
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return archive.Close()
+}
+
+func addArchiveFile(archive *zip.Writer, name string, method uint16, path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	writer, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := io.Copy(writer, source); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"tg-sticker-stiller-bot/services/media"
+	"tg-sticker-stiller-bot/utils"
+
+	"github.com/google/uuid"
+	tg "gopkg.in/telebot.v4"
+)
+
+// maxStaticStickerBytes is the largest static sticker file Telegram accepts.
+const maxStaticStickerBytes = 512 * 1024
+
+// stickerQualities are the WebP qualities tried, best first, until an image
+// fits maxStaticStickerBytes.
+var stickerQualities = []int{90, 75, 60, 40, 20}
+
+// ImageMIMETypes lists the document types that can be turned into stickers.
+var ImageMIMETypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+}
+
+// StickerFromImage turns an uploaded photo or image document into a static
+// sticker: the image is scaled to 512 px on its longest side, encoded as WebP
+// within the size limit and uploaded to Telegram for user. The returned
+// sticker can be copied into a set like any sticker of an existing pack.
+func StickerFromImage(ctx context.Context, bot *tg.Bot, user *tg.User, file tg.File, emoji string) (*tg.Sticker, error) {
+	imagePath, err := DownloadFile(bot, file)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CleanupFiles([]string{imagePath})
+
+	stickerPath, err := encodeImageSticker(ctx, imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CleanupFiles([]string{stickerPath})
+
+	if err := media.Validate(stickerPath, tg.StickerStatic, false); err != nil {
+		return nil, fmt.Errorf("encoded image is not a valid sticker: %w", err)
+	}
+
+	uploaded, err := bot.UploadSticker(user, tg.StickerStatic, tg.FromDisk(stickerPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload sticker file: %w", err)
+	}
+
+	return &tg.Sticker{
+		File:  *uploaded,
+		Type:  tg.StickerRegular,
+		Emoji: emoji,
+	}, nil
+}
+
+// encodeImageSticker writes the image at path as a 512 px WebP sticker,
+// lowering the quality until the file is small enough.
+func encodeImageSticker(ctx context.Context, path string) (string, error) {
+	outPath := filepath.Join(TempDir, fmt.Sprintf("%s.webp", uuid.New().String()))
+
+	for _, quality := range stickerQualities {
+		err := runFFmpeg(ctx, path, outPath, media.StickerSize, "-c:v", "libwebp", "-quality", fmt.Sprint(quality), "-frames:v", "1")
+		if err != nil {
+			os.Remove(outPath)
+			return "", err
+		}
+
+		info, err := os.Stat(outPath)
+		if err != nil {
+			return "", err
+		}
+		if info.Size() <= maxStaticStickerBytes {
+			return outPath, nil
+		}
+	}
+
+	os.Remove(outPath)
+	return "", fmt.Errorf("image does not fit %d bytes as a sticker", maxStaticStickerBytes)
+}
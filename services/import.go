@@ -0,0 +1,257 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	"github.com/google/uuid"
+	tg "gopkg.in/telebot.v4"
+)
+
+// maxImportEntryBytes caps how much of one archive entry is read. Valid
+// sticker files are far smaller.
+const maxImportEntryBytes = 1024 * 1024
+
+// maxImportItems is the most files an archive may hold besides its manifest:
+// as many as the largest set can take.
+const maxImportItems = maxEmojiPerSet
+
+// archiveFormats maps the extensions of importable files to sticker formats.
+var archiveFormats = map[string]string{
+	".webp": tg.StickerStatic,
+	".tgs":  tg.StickerAnimated,
+	".webm": tg.StickerVideo,
+}
+
+// ImportResult holds the items uploaded from an archive, ready to be copied
+// into a new set, and the entries that were skipped.
+type ImportResult struct {
+	Title   string
+	Type    types.StickerType
+	Items   []types.CopyItem
+	Total   int
+	Skipped []SkippedEntry
+}
+
+// SkippedEntry is an archive entry that could not be imported. Reason is
+// shown to the user through its "reason-" i18n key.
+type SkippedEntry struct {
+	File   string
+	Reason types.FailureReason
+}
+
+// IsArchive reports whether document is a ZIP archive.
+func IsArchive(document *tg.Document) bool {
+	if document == nil {
+		return false
+	}
+	switch document.MIME {
+	case "application/zip", "application/x-zip-compressed":
+		return true
+	}
+	return strings.EqualFold(filepath.Ext(document.FileName), ".zip")
+}
+
+// ImportArchive uploads the WebP, TGS and WebM files of the ZIP archive at
+// archivePath as sticker files of user. When the archive has a manifest, its
+// items are imported in their order with their emojis, keywords and the set
+// type; otherwise every sticker file is imported by name as a regular
+// sticker without an emoji. Every entry is validated first and re-encoded
+// once when its problem can be fixed. Archives with more files than the
+// largest set can hold are rejected.
+func ImportArchive(ctx context.Context, bot *tg.Bot, user *tg.User, archivePath string) (*ImportResult, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File)
+	var manifestFile *zip.File
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if path.Base(file.Name) == ManifestFile {
+			manifestFile = file
+			continue
+		}
+		files[file.Name] = file
+		if len(files) > maxImportItems {
+			return nil, tooManyFilesError()
+		}
+	}
+
+	manifest := &Manifest{Type: types.StickerTypeRegular}
+	if manifestFile != nil {
+		if err := readManifest(manifestFile, manifest); err != nil {
+			return nil, err
+		}
+		if len(manifest.Items) > maxImportItems {
+			return nil, tooManyFilesError()
+		}
+	} else {
+		names := make([]string, 0, len(files))
+		for name := range files {
+			if _, ok := archiveFormats[strings.ToLower(path.Ext(name))]; ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			manifest.Items = append(manifest.Items, ManifestItem{File: name, Position: i})
+		}
+	}
+
+	result := &ImportResult{
+		Title: manifest.Title,
+		Type:  manifest.Type,
+		Total: len(manifest.Items),
+	}
+
+	capacity := SetCapacity(manifest.Type)
+	for _, item := range manifest.Items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		file, ok := files[item.File]
+		if !ok {
+			result.Skipped = append(result.Skipped, SkippedEntry{File: item.File, Reason: types.FailureMissingFile})
+			continue
+		}
+		if len(result.Items) == capacity {
+			result.Skipped = append(result.Skipped, SkippedEntry{File: item.File, Reason: types.FailureSetFull})
+			continue
+		}
+
+		copyItem, reason, err := importEntry(ctx, bot, user, file, item, manifest.Type)
+		if err != nil {
+			log.Printf("Skipping archive entry %s: %v", item.File, err)
+			result.Skipped = append(result.Skipped, SkippedEntry{File: item.File, Reason: reason})
+			continue
+		}
+		result.Items = append(result.Items, *copyItem)
+	}
+
+	return result, nil
+}
+
+func tooManyFilesError() *utils.BotError {
+	return utils.NewBotError(
+		fmt.Sprintf("Archive holds more than %d files", maxImportItems),
+		"import-too-many",
+		"ARCHIVE_TOO_MANY_FILES",
+	)
+}
+
+func readManifest(file *zip.File, manifest *Manifest) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer reader.Close()
+
+	if err := json.NewDecoder(io.LimitReader(reader, maxImportEntryBytes)).Decode(manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	switch manifest.Type {
+	case types.StickerTypeRegular, types.StickerTypeMask, types.StickerTypeEmoji:
+	case "":
+		manifest.Type = types.StickerTypeRegular
+	default:
+		return fmt.Errorf("unknown pack type %q in manifest", manifest.Type)
+	}
+
+	sort.SliceStable(manifest.Items, func(i, j int) bool {
+		return manifest.Items[i].Position < manifest.Items[j].Position
+	})
+	return nil
+}
+
+// importEntry extracts one archive entry, checks it for a set of setType and
+// uploads it, returning the item to copy or why the entry was skipped.
+func importEntry(ctx context.Context, bot *tg.Bot, user *tg.User, file *zip.File, item ManifestItem, setType types.StickerType) (*types.CopyItem, types.FailureReason, error) {
+	format, ok := archiveFormats[strings.ToLower(path.Ext(file.Name))]
+	if !ok {
+		return nil, types.FailureUnsupported, errors.New("unsupported file type")
+	}
+	if file.UncompressedSize64 > maxImportEntryBytes {
+		return nil, types.FailureTooLarge, errors.New("file is too large")
+	}
+
+	sticker := tg.Sticker{
+		Type:     tg.StickerSetType(setType),
+		Animated: format == tg.StickerAnimated,
+		Video:    format == tg.StickerVideo,
+	}
+
+	entryPath, err := extractEntry(file, getFileExtension(sticker))
+	if err != nil {
+		return nil, types.FailureExtract, err
+	}
+	defer utils.CleanupFiles([]string{entryPath})
+
+	uploadPath, err := prepareUpload(ctx, entryPath, sticker, setType)
+	if err != nil {
+		return nil, types.FailureFormatInvalid, err
+	}
+	if uploadPath != entryPath {
+		defer utils.CleanupFiles([]string{uploadPath})
+	}
+
+	uploaded, err := bot.UploadSticker(user, format, tg.FromDisk(uploadPath))
+	if err != nil {
+		return nil, uploadFailureReason(err), fmt.Errorf("upload rejected: %w", err)
+	}
+
+	sticker.File = *uploaded
+	sticker.MaskPosition = item.MaskPosition
+	if len(item.Emojis) > 0 {
+		sticker.Emoji = item.Emojis[0]
+	}
+
+	return &types.CopyItem{
+		Sticker:  sticker,
+		Emojis:   item.Emojis,
+		Keywords: item.Keywords,
+	}, "", nil
+}
+
+func extractEntry(file *zip.File, extension string) (string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open entry: %w", err)
+	}
+	defer reader.Close()
+
+	if err := utils.EnsureTempDir(); err != nil {
+		return "", err
+	}
+
+	entryPath := filepath.Join(TempDir, fmt.Sprintf("%s.%s", uuid.New().String(), extension))
+	out, err := os.Create(entryPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, io.LimitReader(reader, maxImportEntryBytes)); err != nil {
+		os.Remove(entryPath)
+		return "", fmt.Errorf("failed to extract entry: %w", err)
+	}
+	return entryPath, nil
+}
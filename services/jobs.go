@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// NewCopyJob persists a new copy job for the given items taken from the
+// sourceSets, leaving out the source items with the unique IDs in leftOut.
+// Items without an emoji are copied with fallbackEmoji.
+func NewCopyJob(repo *db.Repository, userID int64, lang, setName, title string, items []types.CopyItem, stickerType types.StickerType, fallbackEmoji string, sourceSets, leftOut []string) (*db.Job, error) {
+	job := &db.Job{
+		UserID:        userID,
+		LanguageCode:  lang,
+		SetName:       setName,
+		SetTitle:      title,
+		StickerType:   string(stickerType),
+		FallbackEmoji: fallbackEmoji,
+		SourceSets:    strings.Join(sourceSets, ","),
+		LeftOut:       strings.Join(leftOut, ","),
+	}
+	return job, createJob(repo, job, items)
+}
+
+// NewAppendJob persists a job that adds items to the user's existing pack,
+// whose set held baseCount items when the job was created. sourceSets lists
+// every source of the pack, including the earlier ones, and leftOut the unique
+// IDs of the items of new sources that are not added.
+func NewAppendJob(repo *db.Repository, lang string, pack *db.Pack, baseCount int, items []types.CopyItem, sourceSets, leftOut []string) (*db.Job, error) {
+	job := &db.Job{
+		UserID:        pack.UserID,
+		LanguageCode:  lang,
+		SetName:       pack.PackName,
+		SetTitle:      pack.PackTitle,
+		StickerType:   string(StickerTypeOfPack(pack.PackType)),
+		BaseCount:     baseCount,
+		FallbackEmoji: DefaultFallbackEmoji,
+		SourceSets:    strings.Join(sourceSets, ","),
+		LeftOut:       strings.Join(leftOut, ","),
+		GroupName:     pack.GroupName,
+	}
+	return job, createJob(repo, job, items)
+}
+
+// PartNames returns the pack names a copy named name uses when its items are
+// split over parts sets: name itself for a single set, otherwise name_1,
+// name_2, ...
+func PartNames(name, botUsername string, parts int) []string {
+	if parts == 1 {
+		return []string{name}
+	}
+
+	names := make([]string, parts)
+	for i := range names {
+		names[i] = utils.SuffixPackName(name, strconv.Itoa(i+1), botUsername)
+	}
+	return names
+}
+
+// PartTitle returns the title of part of a split copy titled title. The title
+// is shortened so that the part number still fits the set title limit.
+func PartTitle(title string, part int) string {
+	suffix := " " + strconv.Itoa(part)
+
+	runes := []rune(strings.TrimSpace(title))
+	if limit := utils.MaxTitleLength - len(suffix); len(runes) > limit {
+		runes = runes[:limit]
+	}
+	return strings.TrimSpace(string(runes)) + suffix
+}
+
+// NewCopyJobGroup persists the copy jobs for items under the set name built
+// from name. Items that don't fit one set are split over sets named after
+// PartNames and titled after PartTitle, which share one group. Syncing a part
+// treats the items of the other parts as covered by the group.
+func NewCopyJobGroup(repo *db.Repository, userID int64, lang, name, botUsername, title string, items []types.CopyItem, stickerType types.StickerType, fallbackEmoji string, sourceSets, leftOut []string) ([]*db.Job, error) {
+	setName := utils.GenerateSetName(name, botUsername)
+
+	parts := SplitItems(items, stickerType)
+	if len(parts) == 1 {
+		job, err := NewCopyJob(repo, userID, lang, setName, title, items, stickerType, fallbackEmoji, sourceSets, leftOut)
+		if err != nil {
+			return nil, err
+		}
+		return []*db.Job{job}, nil
+	}
+
+	partNames := PartNames(name, botUsername, len(parts))
+	jobs := make([]*db.Job, len(parts))
+	for i, part := range parts {
+		job := &db.Job{
+			UserID:        userID,
+			LanguageCode:  lang,
+			SetName:       utils.GenerateSetName(partNames[i], botUsername),
+			SetTitle:      PartTitle(title, i+1),
+			StickerType:   string(stickerType),
+			FallbackEmoji: fallbackEmoji,
+			SourceSets:    strings.Join(sourceSets, ","),
+			LeftOut:       strings.Join(leftOut, ","),
+			GroupName:     setName,
+		}
+		if err := createJob(repo, job, part); err != nil {
+			return nil, err
+		}
+		jobs[i] = job
+	}
+
+	return jobs, nil
+}
+
+// SplitItems cuts items into chunks that each fit one set of stickerType.
+func SplitItems(items []types.CopyItem, stickerType types.StickerType) [][]types.CopyItem {
+	capacity := SetCapacity(stickerType)
+
+	var parts [][]types.CopyItem
+	for len(items) > capacity {
+		parts = append(parts, items[:capacity])
+		items = items[capacity:]
+	}
+	return append(parts, items)
+}
+
+func createJob(repo *db.Repository, job *db.Job, items []types.CopyItem) error {
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("failed to encode job items: %w", err)
+	}
+
+	job.Items = string(encoded)
+	job.TotalCount = len(items)
+	job.Status = db.JobStatusPending
+
+	return repo.CreateJob(job)
+}
+
+// AppendSourceSets adds names to the comma-separated sourceSets of a pack,
+// skipping empty and already listed names.
+func AppendSourceSets(sourceSets string, names []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+
+	for _, name := range append(strings.Split(sourceSets, ","), names...) {
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		result = append(result, name)
+	}
+
+	return result
+}
+
+// StickerTypeOfPack returns the set type of a stored pack.
+func StickerTypeOfPack(packType db.PackType) types.StickerType {
+	switch packType {
+	case db.PackTypeEmoji:
+		return types.StickerTypeEmoji
+	case db.PackTypeMask:
+		return types.StickerTypeMask
+	default:
+		return types.StickerTypeRegular
+	}
+}
+
+// RunCopyJob runs a persisted copy job and stores its final status.
+func RunCopyJob(ctx context.Context, bot *tg.Bot, repo *db.Repository, job *db.Job, progressCallback ProgressCallback) (*types.CopyResult, error) {
+	var items []types.CopyItem
+	if err := json.Unmarshal([]byte(job.Items), &items); err != nil {
+		setJobStatus(repo, job, db.JobStatusFailed)
+		return nil, fmt.Errorf("failed to decode job items: %w", err)
+	}
+
+	setJobStatus(repo, job, db.JobStatusRunning)
+
+	result, err := CreateStickerSet(ctx, bot, job, items, repo, progressCallback)
+	if err != nil {
+		if botErr, ok := err.(*utils.BotError); ok && botErr.I18nKey == "copy-cancelled" {
+			setJobStatus(repo, job, db.JobStatusCancelled)
+		} else {
+			setJobStatus(repo, job, db.JobStatusFailed)
+		}
+		return nil, err
+	}
+
+	setJobStatus(repo, job, db.JobStatusCompleted)
+	return result, nil
+}
+
+func setJobStatus(repo *db.Repository, job *db.Job, status db.JobStatus) {
+	if err := repo.UpdateJobStatus(job.ID, status); err != nil {
+		log.Printf("Failed to set job %d status to %s: %v", job.ID, status, err)
+		return
+	}
+	job.Status = status
+}
@@ -0,0 +1,80 @@
+// Package media checks sticker files against the Bot API requirements before
+// they are uploaded.
+package media
+
+import (
+	"fmt"
+	"os"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// Side lengths of stickers and custom emoji, in pixels.
+const (
+	StickerSize = 512
+	EmojiSize   = 100
+)
+
+// Bot API limits for sticker files.
+const (
+	maxStaticBytes   = 512 * 1024
+	maxAnimatedBytes = 64 * 1024
+	maxVideoBytes    = 256 * 1024
+	maxSeconds       = 3.0
+	maxAnimatedFPS   = 60
+)
+
+// Problem describes why a file can't be used as a sticker. Fixable problems,
+// such as wrong dimensions or an audio track, go away when the file is
+// re-encoded for the set; the others mean the item has to be skipped.
+type Problem struct {
+	Reason  string
+	Fixable bool
+}
+
+func (p *Problem) Error() string {
+	return p.Reason
+}
+
+func fixable(format string, args ...any) *Problem {
+	return &Problem{Reason: fmt.Sprintf(format, args...), Fixable: true}
+}
+
+func broken(format string, args ...any) *Problem {
+	return &Problem{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Validate checks the sticker file at path in the given format (static,
+// animated or video) for a sticker set, or for a custom emoji set when emoji
+// is true. It returns a *Problem when the file breaks a requirement.
+func Validate(path, format string, emoji bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return broken("failed to read file: %v", err)
+	}
+
+	switch format {
+	case tg.StickerAnimated:
+		return validateTGS(data, emoji)
+	case tg.StickerVideo:
+		return validateWebM(data, emoji)
+	default:
+		return validateWebP(data, emoji)
+	}
+}
+
+// checkDimensions checks that a sticker has one side of exactly 512 px and
+// the other no longer, or that a custom emoji is exactly 100×100.
+func checkDimensions(width, height int, emoji bool) *Problem {
+	if emoji {
+		if width != EmojiSize || height != EmojiSize {
+			return fixable("emoji is %d×%d, expected %d×%d", width, height, EmojiSize, EmojiSize)
+		}
+		return nil
+	}
+
+	if width > StickerSize || height > StickerSize || (width != StickerSize && height != StickerSize) {
+		return fixable("sticker is %d×%d, expected one side of %d and the other at most %d", width, height, StickerSize, StickerSize)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package media
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// lottieHeader holds the Lottie fields Telegram puts requirements on.
+type lottieHeader struct {
+	FrameRate float64 `json:"fr"`
+	InPoint   float64 `json:"ip"`
+	OutPoint  float64 `json:"op"`
+	Width     float64 `json:"w"`
+	Height    float64 `json:"h"`
+}
+
+// validateTGS gzip-decodes an animated sticker and checks its Lottie canvas,
+// frame rate and duration. Only the canvas can be fixed by scaling.
+func validateTGS(data []byte, emoji bool) error {
+	if len(data) > maxAnimatedBytes {
+		return broken("file is %d bytes, at most %d allowed", len(data), maxAnimatedBytes)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return broken("not a gzip file: %v", err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return broken("failed to decompress: %v", err)
+	}
+
+	var header lottieHeader
+	if err := json.Unmarshal(content, &header); err != nil {
+		return broken("invalid Lottie JSON: %v", err)
+	}
+
+	if header.FrameRate <= 0 || header.FrameRate > maxAnimatedFPS {
+		return broken("frame rate is %.0f, at most %d allowed", header.FrameRate, maxAnimatedFPS)
+	}
+	if duration := (header.OutPoint - header.InPoint) / header.FrameRate; duration > maxSeconds {
+		return broken("duration is %.2fs, at most %.0fs allowed", duration, maxSeconds)
+	}
+
+	size := StickerSize
+	if emoji {
+		size = EmojiSize
+	}
+	// Lottie stores sizes as JSON numbers, which exporters may write as 512.0
+	width, height := int(math.Round(header.Width)), int(math.Round(header.Height))
+	if width != size || height != size {
+		return fixable("canvas is %d×%d, expected %d×%d", width, height, size, size)
+	}
+	return nil
+}
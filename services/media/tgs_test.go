@@ -0,0 +1,49 @@
+package media
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func tgsFile(lottie string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(lottie))
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestValidateTGS(t *testing.T) {
+	valid := tgsFile(`{"fr":60,"ip":0,"op":180,"w":512,"h":512,"layers":[]}`)
+	oversized := append(tgsFile(`{"fr":60,"ip":0,"op":180,"w":512,"h":512}`), make([]byte, maxAnimatedBytes)...)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		emoji bool
+		want  string
+	}{
+		{"sticker", valid, false, "valid"},
+		{"sticker with fractional sizes", tgsFile(`{"fr":60.0,"ip":0.0,"op":180.0,"w":512.0,"h":512.0}`), false, "valid"},
+		{"emoji", tgsFile(`{"fr":30,"ip":0,"op":90,"w":100,"h":100}`), true, "valid"},
+		{"sticker of emoji size", tgsFile(`{"fr":60,"ip":0,"op":60,"w":100,"h":100}`), false, "fixable"},
+		{"emoji of sticker size", valid, true, "fixable"},
+		{"too long", tgsFile(`{"fr":60,"ip":0,"op":240,"w":512,"h":512}`), false, "broken"},
+		{"frame rate too high", tgsFile(`{"fr":120,"ip":0,"op":60,"w":512,"h":512}`), false, "broken"},
+		{"no frame rate", tgsFile(`{"ip":0,"op":60,"w":512,"h":512}`), false, "broken"},
+		{"invalid JSON", tgsFile(`{"fr":60,`), false, "broken"},
+		{"oversized", oversized, false, "broken"},
+		{"truncated", valid[:len(valid)/2], false, "broken"},
+		{"not gzip", []byte(`{"fr":60,"ip":0,"op":60,"w":512,"h":512}`), false, "broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTGS(tt.data, tt.emoji)
+			if got := problemKind(err); got != tt.want {
+				t.Errorf("validateTGS() = %v (%s), want %s", err, got, tt.want)
+			}
+		})
+	}
+}
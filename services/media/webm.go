@@ -0,0 +1,224 @@
+package media
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// EBML element IDs read from a WebM header.
+const (
+	idEBML           = 0x1A45DFA3
+	idDocType        = 0x4282
+	idSegment        = 0x18538067
+	idInfo           = 0x1549A966
+	idTimecodeScale  = 0x2AD7B1
+	idDuration       = 0x4489
+	idTracks         = 0x1654AE6B
+	idTrackEntry     = 0xAE
+	idTrackType      = 0x83
+	idCodecID        = 0x86
+	idVideo          = 0xE0
+	idPixelWidth     = 0xB0
+	idPixelHeight    = 0xBA
+	idCluster        = 0x1F43B675
+	trackTypeVideo   = 1
+	trackTypeAudio   = 2
+	defaultTimescale = 1000000
+)
+
+// webmHeader holds what Telegram checks in a video sticker.
+type webmHeader struct {
+	docType   string
+	timescale uint64
+	duration  float64
+	codec     string
+	width     int
+	height    int
+	hasAudio  bool
+}
+
+// validateWebM parses the EBML header of a video sticker and checks its
+// codec, audio, duration, dimensions and size. Re-encoding as VP9 fixes all
+// but the size, which is only checked once nothing else needs fixing.
+func validateWebM(data []byte, emoji bool) error {
+	header := &webmHeader{timescale: defaultTimescale}
+	if err := header.parse(data); err != nil {
+		return err
+	}
+
+	if header.docType != "webm" {
+		return broken("not a WebM file")
+	}
+	if header.codec == "" {
+		return broken("no video track")
+	}
+	if header.codec != "V_VP9" {
+		return fixable("video codec is %q, expected VP9", header.codec)
+	}
+	if header.hasAudio {
+		return fixable("video has an audio track")
+	}
+	if seconds := header.duration * float64(header.timescale) / 1e9; seconds > maxSeconds {
+		return fixable("duration is %.2fs, at most %.0fs allowed", seconds, maxSeconds)
+	}
+
+	if problem := checkDimensions(header.width, header.height, emoji); problem != nil {
+		return problem
+	}
+	if len(data) > maxVideoBytes {
+		return broken("file is %d bytes, at most %d allowed", len(data), maxVideoBytes)
+	}
+	return nil
+}
+
+// parse walks the top-level elements up to the first cluster, descending
+// into the ones that hold the fields of webmHeader.
+func (h *webmHeader) parse(data []byte) error {
+	for len(data) > 0 {
+		id, size, body, rest, err := readElement(data)
+		if err != nil {
+			return err
+		}
+
+		switch id {
+		case idEBML, idSegment, idInfo, idTracks, idVideo:
+			if err := h.parse(body); err != nil {
+				return err
+			}
+		case idTrackEntry:
+			if err := h.parseTrack(body); err != nil {
+				return err
+			}
+		case idDocType:
+			h.docType = string(body)
+		case idTimecodeScale:
+			h.timescale = readUint(body)
+		case idDuration:
+			h.duration = readFloat(body)
+		case idPixelWidth:
+			h.width = int(readUint(body))
+		case idPixelHeight:
+			h.height = int(readUint(body))
+		case idCluster:
+			return nil
+		}
+
+		if size < 0 {
+			return nil
+		}
+		data = rest
+	}
+	return nil
+}
+
+// parseTrack reads one track entry, keeping the codec and dimensions of the
+// video track and noting any audio track.
+func (h *webmHeader) parseTrack(data []byte) error {
+	var trackType uint64
+	var codec string
+	track := &webmHeader{}
+
+	for len(data) > 0 {
+		id, _, body, rest, err := readElement(data)
+		if err != nil {
+			return err
+		}
+
+		switch id {
+		case idTrackType:
+			trackType = readUint(body)
+		case idCodecID:
+			codec = string(body)
+		case idVideo:
+			if err := track.parse(body); err != nil {
+				return err
+			}
+		}
+		data = rest
+	}
+
+	switch trackType {
+	case trackTypeVideo:
+		h.codec = codec
+		h.width = track.width
+		h.height = track.height
+	case trackTypeAudio:
+		h.hasAudio = true
+	}
+	return nil
+}
+
+// readElement splits the first EBML element off data. An element of unknown
+// size (size -1) extends to the end of data; one that is longer than data
+// means the file was cut off.
+func readElement(data []byte) (id uint64, size int64, body, rest []byte, err error) {
+	id, idLength, ok := readVint(data, false)
+	if !ok {
+		return 0, 0, nil, nil, broken("invalid EBML element ID")
+	}
+
+	rawSize, sizeLength, ok := readVint(data[idLength:], true)
+	if !ok {
+		return 0, 0, nil, nil, broken("invalid EBML element size")
+	}
+
+	start := idLength + sizeLength
+	if rawSize == unknownSize(sizeLength) {
+		return id, -1, data[start:], nil, nil
+	}
+	if rawSize > uint64(len(data)-start) {
+		return 0, 0, nil, nil, broken("truncated EBML element")
+	}
+
+	end := start + int(rawSize)
+	return id, int64(rawSize), data[start:end], data[end:], nil
+}
+
+// readVint reads an EBML variable-length integer. IDs keep their length
+// marker bit; sizes have it stripped.
+func readVint(data []byte, stripMarker bool) (uint64, int, bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, false
+	}
+
+	length := 1
+	for mask := byte(0x80); data[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 || len(data) < length {
+		return 0, 0, false
+	}
+
+	value := uint64(data[0])
+	if stripMarker {
+		value &= uint64(0xff >> length)
+	}
+	for _, b := range data[1:length] {
+		value = value<<8 | uint64(b)
+	}
+	return value, length, true
+}
+
+// unknownSize is the reserved all-ones size of the given length.
+func unknownSize(length int) uint64 {
+	return 1<<(7*length) - 1
+}
+
+func readUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func readFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,114 @@
+package media
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// element encodes an EBML element with an eight-byte size.
+func element(id uint64, body ...[]byte) []byte {
+	var data []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(data) > 0 {
+			data = append(data, b)
+		}
+	}
+
+	var content []byte
+	for _, part := range body {
+		content = append(content, part...)
+	}
+
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(content)))
+	size[0] = 0x01
+	return append(append(data, size...), content...)
+}
+
+func uintBody(value uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, value)
+}
+
+func floatBody(value float64) []byte {
+	return binary.BigEndian.AppendUint64(nil, math.Float64bits(value))
+}
+
+type webmFixture struct {
+	docType  string
+	codec    string
+	width    uint64
+	height   uint64
+	duration float64
+	audio    bool
+	padding  int
+}
+
+// bytes builds a WebM file whose segment has an unknown size, as live
+// encoders write it.
+func (f webmFixture) bytes() []byte {
+	tracks := [][]byte{element(idTrackEntry,
+		element(idTrackType, uintBody(trackTypeVideo)),
+		element(idCodecID, []byte(f.codec)),
+		element(idVideo,
+			element(idPixelWidth, uintBody(f.width)),
+			element(idPixelHeight, uintBody(f.height)),
+		),
+	)}
+	if f.audio {
+		tracks = append(tracks, element(idTrackEntry,
+			element(idTrackType, uintBody(trackTypeAudio)),
+			element(idCodecID, []byte("A_OPUS")),
+		))
+	}
+
+	segment := append([]byte{0x18, 0x53, 0x80, 0x67, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		element(idInfo,
+			element(idTimecodeScale, uintBody(defaultTimescale)),
+			element(idDuration, floatBody(f.duration)),
+		)...)
+	segment = append(segment, element(idTracks, tracks...)...)
+	segment = append(segment, element(idCluster, make([]byte, f.padding+4))...)
+
+	return append(element(idEBML, element(idDocType, []byte(f.docType))), segment...)
+}
+
+func TestValidateWebM(t *testing.T) {
+	fixture := func(change func(*webmFixture)) []byte {
+		f := webmFixture{docType: "webm", codec: "V_VP9", width: 512, height: 256, duration: 2900}
+		change(&f)
+		return f.bytes()
+	}
+	valid := fixture(func(*webmFixture) {})
+
+	tests := []struct {
+		name  string
+		data  []byte
+		emoji bool
+		want  string
+	}{
+		{"sticker", valid, false, "valid"},
+		{"emoji", fixture(func(f *webmFixture) { f.width, f.height = 100, 100 }), true, "valid"},
+		{"emoji of sticker size", valid, true, "fixable"},
+		{"VP8 codec", fixture(func(f *webmFixture) { f.codec = "V_VP8" }), false, "fixable"},
+		{"audio track", fixture(func(f *webmFixture) { f.audio = true }), false, "fixable"},
+		{"too long", fixture(func(f *webmFixture) { f.duration = 3500 }), false, "fixable"},
+		{"too large", fixture(func(f *webmFixture) { f.width, f.height = 1280, 720 }), false, "fixable"},
+		{"oversized", fixture(func(f *webmFixture) { f.padding = maxVideoBytes }), false, "broken"},
+		{"oversized with audio", fixture(func(f *webmFixture) { f.padding, f.audio = maxVideoBytes, true }), false, "fixable"},
+		{"Matroska file", fixture(func(f *webmFixture) { f.docType = "matroska" }), false, "broken"},
+		{"truncated element size", valid[:10], false, "broken"},
+		{"truncated header", valid[:len(valid)/2], false, "broken"},
+		{"truncated after header", valid[:len(element(idEBML, element(idDocType, []byte("webm"))))], false, "broken"},
+		{"empty", nil, false, "broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebM(tt.data, tt.emoji)
+			if got := problemKind(err); got != tt.want {
+				t.Errorf("validateWebM() = %v (%s), want %s", err, got, tt.want)
+			}
+		})
+	}
+}
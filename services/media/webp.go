@@ -0,0 +1,57 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// validateWebP checks the dimensions in the WebP header of a static sticker
+// and its size. Re-encoding uses fixed settings that may not make the file any
+// smaller, so only an oversized file with wrong dimensions is fixable.
+func validateWebP(data []byte, emoji bool) error {
+	width, height, err := webpDimensions(data)
+	if err != nil {
+		return err
+	}
+
+	if problem := checkDimensions(width, height, emoji); problem != nil {
+		return problem
+	}
+	if len(data) > maxStaticBytes {
+		return broken("file is %d bytes, at most %d allowed", len(data), maxStaticBytes)
+	}
+	return nil
+}
+
+// webpDimensions reads the canvas size from the first chunk of a WebP file,
+// which is VP8X for extended files, VP8 for lossy and VP8L for lossless ones.
+func webpDimensions(data []byte) (int, int, error) {
+	if len(data) < 30 || !bytes.Equal(data[0:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WEBP")) {
+		return 0, 0, broken("not a WebP file")
+	}
+
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8X":
+		width := 1 + (int(chunk[4]) | int(chunk[5])<<8 | int(chunk[6])<<16)
+		height := 1 + (int(chunk[7]) | int(chunk[8])<<8 | int(chunk[9])<<16)
+		return width, height, nil
+	case "VP8 ":
+		if chunk[3] != 0x9d || chunk[4] != 0x01 || chunk[5] != 0x2a {
+			return 0, 0, broken("invalid VP8 frame header")
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		if chunk[0] != 0x2f {
+			return 0, 0, broken("invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		width := 1 + int(bits&0x3fff)
+		height := 1 + int(bits>>14&0x3fff)
+		return width, height, nil
+	default:
+		return 0, 0, broken("unknown WebP chunk %q", data[12:16])
+	}
+}
@@ -0,0 +1,93 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// webpFile wraps the payload of the first chunk of a WebP file in its RIFF
+// header.
+func webpFile(chunk string, payload []byte) []byte {
+	for len(payload) < 10 {
+		payload = append(payload, 0)
+	}
+
+	data := []byte("RIFF\x00\x00\x00\x00WEBP" + chunk + "\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(data[16:20], uint32(len(payload)))
+	data = append(data, payload...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	return data
+}
+
+func vp8x(width, height int) []byte {
+	w, h := width-1, height-1
+	return webpFile("VP8X", []byte{0, 0, 0, 0, byte(w), byte(w >> 8), byte(w >> 16), byte(h), byte(h >> 8), byte(h >> 16)})
+}
+
+func vp8(width, height int) []byte {
+	payload := []byte{0, 0, 0, 0x9d, 0x01, 0x2a, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(payload[6:8], uint16(width))
+	binary.LittleEndian.PutUint16(payload[8:10], uint16(height))
+	return webpFile("VP8 ", payload)
+}
+
+func vp8l(width, height int) []byte {
+	payload := []byte{0x2f, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(payload[1:5], uint32(width-1)|uint32(height-1)<<14)
+	return webpFile("VP8L", payload)
+}
+
+// problemKind names the outcome of a validation for comparing in tests.
+func problemKind(err error) string {
+	var problem *Problem
+	switch {
+	case err == nil:
+		return "valid"
+	case !errors.As(err, &problem):
+		return "error"
+	case problem.Fixable:
+		return "fixable"
+	default:
+		return "broken"
+	}
+}
+
+func TestValidateWebP(t *testing.T) {
+	oversized := append(vp8l(512, 512), make([]byte, maxStaticBytes)...)
+	oversizedSmall := append(vp8l(300, 300), make([]byte, maxStaticBytes)...)
+	badVP8 := vp8(512, 512)
+	badVP8[23] = 0
+
+	tests := []struct {
+		name  string
+		data  []byte
+		emoji bool
+		want  string
+	}{
+		{"lossless sticker", vp8l(512, 300), false, "valid"},
+		{"lossy sticker", vp8(300, 512), false, "valid"},
+		{"extended sticker", vp8x(512, 512), false, "valid"},
+		{"lossless emoji", vp8l(100, 100), true, "valid"},
+		{"extended emoji", vp8x(100, 100), true, "valid"},
+		{"sticker without a 512 px side", vp8l(300, 300), false, "fixable"},
+		{"sticker larger than 512 px", vp8x(1024, 512), false, "fixable"},
+		{"emoji of sticker size", vp8(512, 512), true, "fixable"},
+		{"oversized", oversized, false, "broken"},
+		{"oversized without a 512 px side", oversizedSmall, false, "fixable"},
+		{"truncated", vp8l(512, 512)[:20], false, "broken"},
+		{"empty", nil, false, "broken"},
+		{"not a WebP file", []byte("GIF89a" + string(make([]byte, 40))), false, "broken"},
+		{"invalid VP8 frame", badVP8, false, "broken"},
+		{"unknown chunk", webpFile("ALPH", nil), false, "broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebP(tt.data, tt.emoji)
+			if got := problemKind(err); got != tt.want {
+				t.Errorf("validateWebP() = %v (%s), want %s", err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"strings"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// MergeSets combines the stickers of sets in order, keeping only the first
+// occurrence of every file. It returns the combined stickers and how many
+// duplicates were dropped.
+func MergeSets(sets []types.StickerSet) ([]tg.Sticker, int) {
+	var stickers []tg.Sticker
+	seen := make(map[string]bool)
+	duplicates := 0
+
+	for _, set := range sets {
+		for _, sticker := range set.Stickers {
+			if seen[sticker.UniqueID] {
+				duplicates++
+				continue
+			}
+			seen[sticker.UniqueID] = true
+			stickers = append(stickers, sticker)
+		}
+	}
+
+	return stickers, duplicates
+}
+
+// MergedTitle joins the titles of sets into one that fits a set title.
+func MergedTitle(sets []types.StickerSet) string {
+	titles := make([]string, len(sets))
+	for i, set := range sets {
+		titles[i] = set.Title
+	}
+
+	title := []rune(strings.Join(titles, " + "))
+	if len(title) > utils.MaxTitleLength {
+		title = title[:utils.MaxTitleLength]
+	}
+	return strings.TrimSpace(string(title))
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"sync"
+	"tg-sticker-stiller-bot/db"
 	"tg-sticker-stiller-bot/types"
 
 	tg "gopkg.in/telebot.v4"
@@ -10,8 +12,16 @@ import (
 type SessionState string
 
 const (
-	StateIdle               SessionState = ""
-	StateWaitingForPackName SessionState = "waiting_for_pack_name"
+	StateIdle                    SessionState = ""
+	StateWaitingForPackName      SessionState = "waiting_for_pack_name"
+	StateWaitingForSlug          SessionState = "waiting_for_slug"
+	StateWaitingForFallbackEmoji SessionState = "waiting_for_fallback_emoji"
+	StateWaitingForSelection     SessionState = "waiting_for_selection"
+	StateWaitingForQueueChoice   SessionState = "waiting_for_queue_choice"
+	StateCollectingMergeSets     SessionState = "collecting_merge_sets"
+	StateWaitingForAddSource     SessionState = "waiting_for_add_source"
+	StateCollectingImages        SessionState = "collecting_images"
+	StateCopying                 SessionState = "copying"
 )
 
 type Session struct {
@@ -19,10 +29,21 @@ type Session struct {
 	OriginalPackName string
 	OriginalItems    []tg.Sticker
 	Title            string
+	CopyTitle        string
+	ProposedSlug     string
 	Name             string
 	FullLink         string
 	PackType         types.StickerType
+	SourceType       types.StickerType
 	ProgressMsgID    int
+	FallbackEmoji    string
+	Queue            []types.StickerSet
+	SourceSets       []string
+	LeftOut          []string
+	MergeSets        []types.StickerSet
+	ImportedItems    map[string]types.CopyItem
+	TargetPack       *db.Pack
+	Cancel           context.CancelFunc
 }
 
 type SessionStore struct {
@@ -60,3 +81,20 @@ func (s *SessionStore) Clear(userID int64) {
 
 	delete(s.sessions, userID)
 }
+
+// Update applies fn to a copy of the user's session and stores the result
+// while holding the store lock, so messages handled at the same time don't
+// overwrite each other's changes.
+func (s *SessionStore) Update(userID int64, fn func(session *Session)) Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := Session{State: StateIdle}
+	if existing, exists := s.sessions[userID]; exists {
+		session = *existing
+	}
+
+	fn(&session)
+	s.sessions[userID] = &session
+	return session
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"tg-sticker-stiller-bot/types"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// PlanSync compares a copied pack with its sources. copies maps the unique
+// IDs of source stickers to the unique IDs of their copies, or to an empty
+// string for the ones the pack leaves out. covered holds every source sticker
+// recorded for the pack or, for a split copy, for any of its parts. It returns
+// the source stickers added since the pack was copied and, keyed by source
+// unique ID, the copies whose source sticker is gone. Copies the owner
+// removed by hand are not added again.
+func PlanSync(sources []types.StickerSet, target *types.StickerSet, copies map[string]string, covered map[string]bool) ([]tg.Sticker, map[string]tg.Sticker) {
+	sourceStickers, _ := MergeSets(sources)
+
+	inSource := make(map[string]bool)
+	for _, sticker := range sourceStickers {
+		inSource[sticker.UniqueID] = true
+	}
+
+	inTarget := make(map[string]tg.Sticker)
+	for _, sticker := range target.Stickers {
+		inTarget[sticker.UniqueID] = sticker
+	}
+
+	var added []tg.Sticker
+	for _, sticker := range sourceStickers {
+		if _, recorded := copies[sticker.UniqueID]; recorded || covered[sticker.UniqueID] {
+			continue
+		}
+		// Packs copied before items were recorded share unique IDs with
+		// their sources when the file_id was reused
+		if _, present := inTarget[sticker.UniqueID]; present {
+			continue
+		}
+		added = append(added, sticker)
+	}
+
+	removed := make(map[string]tg.Sticker)
+	for sourceID, copyID := range copies {
+		if copyID == "" || inSource[sourceID] {
+			continue
+		}
+		if sticker, present := inTarget[copyID]; present {
+			removed[sourceID] = sticker
+		}
+	}
+
+	return added, removed
+}
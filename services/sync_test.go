@@ -0,0 +1,138 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"tg-sticker-stiller-bot/types"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+func stickerSet(name string, uniqueIDs ...string) types.StickerSet {
+	set := types.StickerSet{Name: name}
+	for _, uniqueID := range uniqueIDs {
+		set.Stickers = append(set.Stickers, tg.Sticker{File: tg.File{UniqueID: uniqueID}})
+	}
+	return set
+}
+
+func TestPlanSync(t *testing.T) {
+	tests := []struct {
+		name        string
+		sources     []types.StickerSet
+		target      types.StickerSet
+		copies      map[string]string
+		covered     []string
+		wantAdded   []string
+		wantRemoved map[string]string
+	}{
+		{
+			name:    "selection keeps deselected items out",
+			sources: []types.StickerSet{stickerSet("src", "A", "B", "C")},
+			target:  stickerSet("copy", "a"),
+			copies:  map[string]string{"A": "a", "B": "", "C": ""},
+		},
+		{
+			name:      "selection picks up new items",
+			sources:   []types.StickerSet{stickerSet("src", "A", "B", "C", "D")},
+			target:    stickerSet("copy", "a"),
+			copies:    map[string]string{"A": "a", "B": "", "C": ""},
+			wantAdded: []string{"D"},
+		},
+		{
+			name:    "split part skips the items of other parts",
+			sources: []types.StickerSet{stickerSet("src", "A", "B", "C", "D")},
+			target:  stickerSet("copy_2", "c", "d"),
+			copies:  map[string]string{"C": "c", "D": "d"},
+			covered: []string{"A", "B"},
+		},
+		{
+			name:      "split part picks up new items",
+			sources:   []types.StickerSet{stickerSet("src", "A", "B", "C", "D", "E")},
+			target:    stickerSet("copy_2", "c", "d"),
+			copies:    map[string]string{"C": "c", "D": "d"},
+			covered:   []string{"A", "B"},
+			wantAdded: []string{"E"},
+		},
+		{
+			name:    "split part skips new items another part added",
+			sources: []types.StickerSet{stickerSet("src", "A", "B", "C", "D", "E")},
+			target:  stickerSet("copy_1", "a", "b"),
+			copies:  map[string]string{"A": "a", "B": "b"},
+			covered: []string{"C", "D", "E"},
+		},
+		{
+			name:    "add keeps the rest of the source out",
+			sources: []types.StickerSet{stickerSet("src", "A"), stickerSet("more", "X", "Y", "Z")},
+			target:  stickerSet("copy", "a", "x"),
+			copies:  map[string]string{"A": "a", "X": "x", "Y": "", "Z": ""},
+		},
+		{
+			name:      "add picks up items new to the added source",
+			sources:   []types.StickerSet{stickerSet("src", "A"), stickerSet("more", "X", "Y", "Z", "W")},
+			target:    stickerSet("copy", "a", "x"),
+			copies:    map[string]string{"A": "a", "X": "x", "Y": "", "Z": ""},
+			wantAdded: []string{"W"},
+		},
+		{
+			name:        "removed source items are deleted",
+			sources:     []types.StickerSet{stickerSet("src", "B")},
+			target:      stickerSet("copy", "a", "b"),
+			copies:      map[string]string{"A": "a", "B": "b"},
+			wantRemoved: map[string]string{"A": "a"},
+		},
+		{
+			name:    "copies removed by hand are not added again",
+			sources: []types.StickerSet{stickerSet("src", "A", "B")},
+			target:  stickerSet("copy", "b"),
+			copies:  map[string]string{"A": "a", "B": "b"},
+		},
+		{
+			name:    "removed left out items are ignored",
+			sources: []types.StickerSet{stickerSet("src", "A")},
+			target:  stickerSet("copy", "a"),
+			copies:  map[string]string{"A": "a", "B": ""},
+		},
+		{
+			name:      "items shared with the target are not added",
+			sources:   []types.StickerSet{stickerSet("src", "A", "B")},
+			target:    stickerSet("copy", "A"),
+			copies:    map[string]string{},
+			wantAdded: []string{"B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			covered := make(map[string]bool)
+			for sourceID := range tt.copies {
+				covered[sourceID] = true
+			}
+			for _, sourceID := range tt.covered {
+				covered[sourceID] = true
+			}
+
+			added, removed := PlanSync(tt.sources, &tt.target, tt.copies, covered)
+
+			var addedIDs []string
+			for _, sticker := range added {
+				addedIDs = append(addedIDs, sticker.UniqueID)
+			}
+			if !reflect.DeepEqual(addedIDs, tt.wantAdded) {
+				t.Errorf("added = %v, want %v", addedIDs, tt.wantAdded)
+			}
+
+			removedIDs := make(map[string]string)
+			for sourceID, sticker := range removed {
+				removedIDs[sourceID] = sticker.UniqueID
+			}
+			wantRemoved := tt.wantRemoved
+			if wantRemoved == nil {
+				wantRemoved = map[string]string{}
+			}
+			if !reflect.DeepEqual(removedIDs, wantRemoved) {
+				t.Errorf("removed = %v, want %v", removedIDs, wantRemoved)
+			}
+		})
+	}
+}
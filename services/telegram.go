@@ -3,6 +3,8 @@ package services
 import (
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"strconv"
 	"strings"
 	"tg-sticker-stiller-bot/types"
 	"tg-sticker-stiller-bot/utils"
@@ -10,15 +12,32 @@ import (
 	tg "gopkg.in/telebot.v4"
 )
 
-func FetchStickerSet(bot *tg.Bot, name string) (*types.StickerSet, error) {
+// CopyItemsFromStickers wraps fetched stickers for copying. The Bot API
+// exposes a single emoji per sticker and no keywords, so only that emoji is
+// carried over; items built from other sources may fill in more.
+func CopyItemsFromStickers(stickers []tg.Sticker) []types.CopyItem {
+	items := make([]types.CopyItem, len(stickers))
+	for i, sticker := range stickers {
+		items[i] = types.CopyItem{Sticker: sticker}
+		if sticker.Emoji != "" {
+			items[i].Emojis = []string{sticker.Emoji}
+		}
+	}
+	return items
+}
+
+// FetchSet fetches any sticker set by name. The returned set carries the
+// real set type (regular, mask or custom emoji) regardless of how the link
+// that named it looked.
+func FetchSet(bot *tg.Bot, name string) (*types.StickerSet, error) {
 	return utils.WithRetry(func() (*types.StickerSet, error) {
 		stickerSet, err := bot.StickerSet(name)
 		if err != nil {
-			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "404") {
+			if isSetNotFoundError(err) {
 				log.Printf("Sticker set not found: %s", name)
 				return nil, utils.NewBotError(
 					fmt.Sprintf("Sticker set not found: %s", name),
-					"sticker-not-found",
+					"pack-not-found",
 					"STICKER_SET_NOT_FOUND",
 				)
 			}
@@ -30,38 +49,108 @@ func FetchStickerSet(bot *tg.Bot, name string) (*types.StickerSet, error) {
 			)
 		}
 
+		setType := types.StickerType(stickerSet.Type)
+		if setType == "" {
+			setType = types.StickerTypeRegular
+		}
+
 		return &types.StickerSet{
 			Name:     stickerSet.Name,
 			Title:    stickerSet.Title,
+			Type:     setType,
 			Stickers: stickerSet.Stickers,
 		}, nil
 	})
 }
 
-func FetchEmojiSet(bot *tg.Bot, name string) (*types.EmojiSet, error) {
-	return utils.WithRetry(func() (*types.EmojiSet, error) {
-		stickerSet, err := bot.StickerSet(name)
+// maxFreeNameProbes caps how many names FindFreePackName checks, since every
+// name costs a request per part.
+const maxFreeNameProbes = 4
+
+// FindFreePackName returns the first of name, name_2, name_3 and a name with
+// a short random suffix whose sets don't exist on Telegram yet, or an empty
+// string when none is free. A copy split over parts sets needs every part
+// name to be free. Names that can't be checked are skipped.
+func FindFreePackName(bot *tg.Bot, name string, parts int) string {
+	candidates := []string{name}
+	for i := 2; len(candidates) < maxFreeNameProbes-1; i++ {
+		candidates = append(candidates, utils.SuffixPackName(name, strconv.Itoa(i), bot.Me.Username))
+	}
+	candidates = append(candidates, utils.SuffixPackName(name, randomSuffix(), bot.Me.Username))
+
+	for _, candidate := range candidates {
+		free, err := isPackNameFree(bot, candidate, parts)
 		if err != nil {
-			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "404") {
-				log.Printf("Emoji set not found: %s", name)
-				return nil, utils.NewBotError(
-					fmt.Sprintf("Emoji set not found: %s", name),
-					"emoji-not-found",
-					"EMOJI_SET_NOT_FOUND",
-				)
+			log.Printf("Skipping pack name %s: %v", candidate, err)
+			continue
+		}
+		if free {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// IsPackNameTaken reports whether a set of a copy named name, split over
+// parts sets, already exists on Telegram.
+func IsPackNameTaken(bot *tg.Bot, name string, parts int) (bool, error) {
+	for _, partName := range PartNames(name, bot.Me.Username, parts) {
+		_, err := FetchSet(bot, utils.GenerateSetName(partName, bot.Me.Username))
+		if err == nil {
+			return true, nil
+		}
+		if !utils.IsBotError(err, "pack-not-found") {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// isPackNameFree asks Telegram once, without retrying, whether no set of a
+// copy named name, split over parts sets, exists yet.
+func isPackNameFree(bot *tg.Bot, name string, parts int) (bool, error) {
+	for _, partName := range PartNames(name, bot.Me.Username, parts) {
+		_, err := bot.StickerSet(utils.GenerateSetName(partName, bot.Me.Username))
+		if err == nil {
+			return false, nil
+		}
+		if !isSetNotFoundError(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func isSetNotFoundError(err error) bool {
+	return strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "STICKERSET_INVALID")
+}
+
+func randomSuffix() string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	suffix := make([]byte, 4)
+	for i := range suffix {
+		suffix[i] = alphabet[rand.IntN(len(alphabet))]
+	}
+	return string(suffix)
+}
+
+// FetchCustomEmojiSetName returns the name of the set the first of the given
+// custom emoji belongs to.
+func FetchCustomEmojiSetName(bot *tg.Bot, customEmojiIDs []string) (string, error) {
+	return utils.WithRetry(func() (string, error) {
+		stickers, err := bot.CustomEmojiStickers(customEmojiIDs)
+		if err != nil {
+			log.Printf("Telegram API error fetching custom emoji: %v", err)
+			return "", err
+		}
+
+		for _, sticker := range stickers {
+			if sticker.SetName != "" {
+				return sticker.SetName, nil
 			}
-			log.Printf("Telegram API error fetching emoji set: %v", err)
-			return nil, utils.NewBotError(
-				fmt.Sprintf("Telegram API error: %v", err),
-				"fetch-emoji-failed",
-				"TELEGRAM_API_ERROR",
-			)
 		}
 
-		return &types.EmojiSet{
-			Name:     stickerSet.Name,
-			Title:    stickerSet.Title,
-			Stickers: stickerSet.Stickers,
-		}, nil
+		return "", nil
 	})
 }
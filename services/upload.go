@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/services/media"
 	"tg-sticker-stiller-bot/types"
 	"tg-sticker-stiller-bot/utils"
 	"time"
@@ -14,129 +17,498 @@ import (
 
 type ProgressCallback func(current, total int)
 
-func CreateStickerSet(bot *tg.Bot, userID int64, botname string, title string, stickers []tg.Sticker, stickerType types.StickerType, repo *db.Repository, progressCallback ProgressCallback) (string, error) {
-	downloadedStickers := DownloadAllStickers(bot, stickers)
-	if len(downloadedStickers) == 0 {
-		log.Printf("No stickers could be downloaded for user %d", userID)
-		return "", fmt.Errorf("no stickers could be downloaded")
+// DefaultFallbackEmoji is assigned to items without an emoji unless the user
+// picks another one.
+const DefaultFallbackEmoji = "😀"
+
+// Bot API limits for a single sticker.
+const (
+	maxEmojisPerSticker   = 20
+	maxKeywordsPerSticker = 20
+)
+
+// Bot API limits for the number of items in one set.
+const (
+	maxStickersPerSet = 120
+	maxEmojiPerSet    = 200
+)
+
+// SetCapacity returns how many items a set of the given type can hold.
+func SetCapacity(stickerType types.StickerType) int {
+	if stickerType == types.StickerTypeEmoji {
+		return maxEmojiPerSet
+	}
+	return maxStickersPerSet
+}
+
+// CreateStickerSet copies the job's stickers into a new set, or into the
+// existing one for append jobs, recording every added sticker so an
+// interrupted job can continue where it stopped. Stickers already recorded for
+// the job are skipped and the set is only created when nothing has been added
+// yet. Cancelling ctx stops the copy between items.
+// Items that cannot be downloaded, fail validation or are rejected by
+// Telegram are skipped and listed in the result. The job only fails when
+// Telegram refuses to create the set.
+func CreateStickerSet(ctx context.Context, bot *tg.Bot, job *db.Job, items []types.CopyItem, repo *db.Repository, progressCallback ProgressCallback) (*types.CopyResult, error) {
+	statuses, err := repo.GetJobItemStatuses(job.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	filePaths := make([]string, len(downloadedStickers))
-	for i, ds := range downloadedStickers {
-		filePaths[i] = ds.Path
+	var positions []int
+	for i := range items {
+		if _, processed := statuses[i]; !processed {
+			positions = append(positions, i)
+		}
 	}
-	defer utils.CleanupFiles(filePaths)
 
-	normalizedName := utils.NormalizePackName(title)
-	setName := utils.GenerateSetName(normalizedName, botname)
+	// Items beyond the capacity of the set are never sent to Telegram
+	capacity := SetCapacity(types.StickerType(job.StickerType)) - job.BaseCount
+	for len(positions) > 0 && positions[len(positions)-1] >= capacity {
+		position := positions[len(positions)-1]
+		recordJobFailure(repo, job, position, items[position].Sticker, types.FailureSetFull)
+		positions = positions[:len(positions)-1]
+	}
 
-	user := &tg.User{ID: userID}
+	setName := job.SetName
+	user := &tg.User{ID: job.UserID}
 
 	var telegramStickerType tg.StickerSetType
-	var packLink string
 	var dbPackType db.PackType
+	packLink := PackLink(setName, types.StickerType(job.StickerType))
 
-	if stickerType == types.StickerTypeEmoji {
+	switch types.StickerType(job.StickerType) {
+	case types.StickerTypeEmoji:
 		telegramStickerType = tg.StickerCustomEmoji
-		packLink = fmt.Sprintf("https://t.me/addemoji/%s", setName)
 		dbPackType = db.PackTypeEmoji
-	} else {
+	case types.StickerTypeMask:
+		telegramStickerType = tg.StickerMask
+		dbPackType = db.PackTypeMask
+	default:
 		telegramStickerType = tg.StickerRegular
-		packLink = fmt.Sprintf("https://t.me/addstickers/%s", setName)
 		dbPackType = db.PackTypeSticker
 	}
 
-	// Create set with first sticker only
-	firstSticker := downloadedStickers[0]
-	emoji := firstSticker.Sticker.Emoji
-	if emoji == "" {
-		emoji = "😀"
+	totalStickers := len(items)
+
+	// start from 0 more user friendly
+	if progressCallback != nil && totalStickers > 1 {
+		progressCallback(job.AddedCount, totalStickers)
 	}
 
-	firstInput := tg.InputSticker{
-		File:     tg.FromDisk(firstSticker.Path),
-		Format:   utils.GetStickerFormat(firstSticker.Sticker),
-		Emojis:   []string{emoji},
-		Keywords: []string{},
+	for i, position := range positions {
+		if ctx.Err() != nil {
+			if job.AddedCount > 0 {
+				savePack(repo, job, dbPackType, packLink)
+				recordPackItems(bot, repo, job, items, statuses)
+			}
+			return nil, copyCancelledError(job)
+		}
+
+		item := items[position]
+		creating := job.BaseCount == 0 && job.AddedCount == 0
+		started := time.Now()
+
+		strategy, reason, err := copyItem(ctx, bot, user, job, telegramStickerType, item)
+		if err != nil {
+			if botErr, ok := err.(*utils.BotError); ok {
+				return nil, botErr
+			}
+			if ctx.Err() != nil {
+				continue
+			}
+			var creationErr *setCreationError
+			if errors.As(err, &creationErr) {
+				log.Printf("Failed to create sticker set: %v", err)
+				return nil, err
+			}
+
+			log.Printf("Failed to add sticker %d/%d to set: %v", position+1, totalStickers, err)
+			// Continue adding other stickers even if one fails
+			recordJobFailure(repo, job, position, item.Sticker, reason)
+		} else {
+			recordJobItem(repo, job, position, item.Sticker, strategy, time.Since(started))
+			statuses[position] = db.JobItemAdded
+			if !creating {
+				placeSticker(bot, setName, position, statuses)
+			}
+		}
+
+		// Update progress every 10 stickers or on the last one
+		if progressCallback != nil {
+			if (i+1)%10 == 0 || i+1 == len(positions) {
+				progressCallback(job.AddedCount, totalStickers)
+			}
+		}
+
+		// Delay to avoid rate limiting (1ms between 5 stickers)
+		if i < len(positions)-1 && i%5 != 0 {
+			time.Sleep(time.Millisecond)
+		}
 	}
 
-	stickerSet := &tg.StickerSet{
-		Type:  telegramStickerType,
-		Name:  setName,
-		Title: title,
-		Input: []tg.InputSticker{firstInput},
+	if job.AddedCount == 0 {
+		log.Printf("No stickers could be copied for user %d", job.UserID)
+		return nil, fmt.Errorf("no stickers could be copied")
+	}
+
+	savePack(repo, job, dbPackType, packLink)
+	recordPackItems(bot, repo, job, items, statuses)
+
+	return copyResult(repo, job, items, packLink), nil
+}
+
+// copyItem adds sticker to the job's set, creating the set when it does not
+// exist yet. The source file_id is reused first; the sticker is only
+// downloaded and uploaded from disk when Telegram rejects it. Stickers copied
+// into a set of another type are always downloaded and resized first; one
+// that can't be resized fails on its own, like any item rejected before it
+// reaches Telegram.
+func copyItem(ctx context.Context, bot *tg.Bot, user *tg.User, job *db.Job, setType tg.StickerSetType, item types.CopyItem) (types.CopyStrategy, types.FailureReason, error) {
+	sticker := item.Sticker
+	strategy := types.StrategyUpload
+
+	if NeedsConversion(sticker, types.StickerType(job.StickerType)) {
+		strategy = types.StrategyConvert
+	} else {
+		// A file_id sends no file, so there is nothing to validate: it names a
+		// file Telegram already accepted for a set of the same kind, or one
+		// the bot validated before uploading it. Files sent from disk below
+		// are always validated.
+		err := addInputSticker(bot, user, job, setType, inputSticker(item, tg.File{FileID: sticker.FileID}, job.FallbackEmoji))
+		if err == nil {
+			return types.StrategyFileID, "", nil
+		}
+		if isNameTakenError(err) {
+			return types.StrategyFileID, "", nameTakenError(job)
+		}
+
+		log.Printf("Telegram rejected file_id of sticker %s, uploading it instead: %v", sticker.UniqueID, err)
+	}
+
+	filePath, err := DownloadSticker(ctx, bot, sticker)
+	if err != nil {
+		return strategy, types.FailureDownload, err
+	}
+	defer utils.CleanupFiles([]string{filePath})
+
+	if strategy == types.StrategyConvert {
+		convertedPath, err := ConvertSticker(ctx, filePath, sticker, types.StickerType(job.StickerType))
+		if err != nil {
+			return strategy, types.FailureFormatInvalid, err
+		}
+		defer utils.CleanupFiles([]string{convertedPath})
+		filePath = convertedPath
 	}
 
-	err := bot.CreateStickerSet(user, stickerSet)
+	uploadPath, err := prepareUpload(ctx, filePath, sticker, types.StickerType(job.StickerType))
+	if err != nil {
+		return strategy, types.FailureFormatInvalid, err
+	}
+	if uploadPath != filePath {
+		defer utils.CleanupFiles([]string{uploadPath})
+		filePath = uploadPath
+	}
+
+	err = addInputSticker(bot, user, job, setType, inputSticker(item, tg.FromDisk(filePath), job.FallbackEmoji))
 	if err != nil {
 		if isNameTakenError(err) {
-			log.Printf("Sticker set name already exists: %s for user %d", title, userID)
-			return "", utils.NewBotError(
-				fmt.Sprintf("Sticker set name already exists: %s", title),
-				"name-taken",
-				"STICKER_SET_NAME_TAKEN",
-			)
-		}
-		log.Printf("Failed to create sticker set: %v", err)
+			return strategy, "", nameTakenError(job)
+		}
+		return strategy, uploadFailureReason(err), err
+	}
+
+	return strategy, "", nil
+}
+
+// prepareUpload checks the sticker file at path against the requirements of
+// the target set type before it is sent to Telegram. A file with a fixable
+// problem is re-encoded once; the path of the new file is returned then.
+func prepareUpload(ctx context.Context, path string, sticker tg.Sticker, target types.StickerType) (string, error) {
+	format := utils.GetStickerFormat(sticker)
+	emoji := target == types.StickerTypeEmoji
+
+	err := media.Validate(path, format, emoji)
+	if err == nil {
+		return path, nil
+	}
+
+	var problem *media.Problem
+	if !errors.As(err, &problem) || !problem.Fixable {
+		log.Printf("Sticker %s can't be uploaded: %v", sticker.UniqueID, err)
 		return "", err
 	}
 
-	// Add remaining stickers one by one
-	totalStickers := len(downloadedStickers)
+	log.Printf("Re-encoding sticker %s before upload: %v", sticker.UniqueID, problem)
+	fixedPath, err := ConvertSticker(ctx, path, sticker, target)
+	if err != nil {
+		return "", err
+	}
 
-	// start from 0 more user friendly
-	if progressCallback != nil && totalStickers > 1 {
-		progressCallback(0, totalStickers)
+	if err := media.Validate(fixedPath, format, emoji); err != nil {
+		utils.CleanupFiles([]string{fixedPath})
+		log.Printf("Sticker %s is still invalid after re-encoding: %v", sticker.UniqueID, err)
+		return "", err
 	}
+	return fixedPath, nil
+}
+
+func addInputSticker(bot *tg.Bot, user *tg.User, job *db.Job, setType tg.StickerSetType, input tg.InputSticker) error {
+	if job.BaseCount > 0 || job.AddedCount > 0 {
+		return bot.AddStickerToSet(user, job.SetName, input)
+	}
+
+	// Create set with first sticker only
+	stickerSet := &tg.StickerSet{
+		Type:  setType,
+		Name:  job.SetName,
+		Title: job.SetTitle,
+		Input: []tg.InputSticker{input},
+	}
+	if err := bot.CreateStickerSet(user, stickerSet); err != nil {
+		return &setCreationError{err: err}
+	}
+	return nil
+}
+
+// setCreationError is returned when Telegram refuses to create the set with
+// its first item. Unlike items that fail before reaching Telegram, it stops
+// the whole copy.
+type setCreationError struct {
+	err error
+}
+
+func (e *setCreationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *setCreationError) Unwrap() error {
+	return e.err
+}
 
-	for i := 1; i < totalStickers; i++ {
-		stickerData := downloadedStickers[i]
-		emoji := stickerData.Sticker.Emoji
-		if emoji == "" {
-			emoji = "😀"
+// inputSticker describes item for Telegram with all of its emojis, keywords
+// and mask position. fallbackEmoji is used when the item has no emoji.
+func inputSticker(item types.CopyItem, file tg.File, fallbackEmoji string) tg.InputSticker {
+	emojis := item.Emojis
+	if len(emojis) == 0 && item.Emoji != "" {
+		emojis = []string{item.Emoji}
+	}
+	if len(emojis) == 0 {
+		if fallbackEmoji == "" {
+			fallbackEmoji = DefaultFallbackEmoji
 		}
+		emojis = []string{fallbackEmoji}
+	}
+	if len(emojis) > maxEmojisPerSticker {
+		emojis = emojis[:maxEmojisPerSticker]
+	}
 
-		inputSticker := tg.InputSticker{
-			File:     tg.FromDisk(stickerData.Path),
-			Format:   utils.GetStickerFormat(stickerData.Sticker),
-			Emojis:   []string{emoji},
-			Keywords: []string{},
+	keywords := item.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+	if len(keywords) > maxKeywordsPerSticker {
+		keywords = keywords[:maxKeywordsPerSticker]
+	}
+
+	return tg.InputSticker{
+		File:         file,
+		Format:       utils.GetStickerFormat(item.Sticker),
+		MaskPosition: item.MaskPosition,
+		Emojis:       emojis,
+		Keywords:     keywords,
+	}
+}
+
+func copyResult(repo *db.Repository, job *db.Job, items []types.CopyItem, packLink string) *types.CopyResult {
+	result := &types.CopyResult{
+		PackLink: packLink,
+		Added:    job.AddedCount,
+		Total:    job.TotalCount,
+	}
+
+	failedItems, err := repo.GetFailedJobItems(job.ID)
+	if err != nil {
+		log.Printf("Failed to load failed items of job %d: %v", job.ID, err)
+		return result
+	}
+
+	for _, item := range failedItems {
+		failed := types.FailedItem{
+			Position: item.Position,
+			Reason:   types.FailureReason(item.Reason),
+		}
+		if item.Position < len(items) {
+			failed.Emoji = items[item.Position].Emoji
 		}
+		result.Failed = append(result.Failed, failed)
+	}
 
-		err := bot.AddStickerToSet(user, setName, inputSticker)
-		if err != nil {
-			log.Printf("Failed to add sticker %d/%d to set: %v", i+1, totalStickers, err)
-			// Continue adding other stickers even if one fails
+	return result
+}
+
+// PackLink returns the public add link of a set.
+func PackLink(setName string, stickerType types.StickerType) string {
+	if stickerType == types.StickerTypeEmoji {
+		return fmt.Sprintf("https://t.me/addemoji/%s", setName)
+	}
+	return fmt.Sprintf("https://t.me/addstickers/%s", setName)
+}
+
+func savePack(repo *db.Repository, job *db.Job, packType db.PackType, packLink string) {
+	pack := &db.Pack{
+		UserID:       job.UserID,
+		PackName:     job.SetName,
+		PackTitle:    job.SetTitle,
+		PackType:     packType,
+		PackLink:     packLink,
+		StickerCount: job.BaseCount + job.AddedCount,
+		SourceSets:   job.SourceSets,
+		GroupName:    job.GroupName,
+	}
+	if err := repo.UpsertPack(pack); err != nil {
+		log.Printf("Failed to save pack to database: %v", err)
+	}
+}
+
+// recordPackItems links the job's added items to their copies so the pack can
+// later be synced with its sources. The job's items are expected to be the
+// last ones of the set, in source order. Items that were not added and the
+// items the job leaves out are recorded without a copy.
+func recordPackItems(bot *tg.Bot, repo *db.Repository, job *db.Job, items []types.CopyItem, statuses map[int]db.JobItemStatus) {
+	var packItems []db.PackItem
+	leaveOut := func(sourceID string) {
+		packItems = append(packItems, db.PackItem{
+			UserID:         job.UserID,
+			PackName:       job.SetName,
+			SourceUniqueID: sourceID,
+		})
+	}
+
+	if job.LeftOut != "" {
+		for _, sourceID := range strings.Split(job.LeftOut, ",") {
+			leaveOut(sourceID)
 		}
+	}
 
-		// Update progress every 10 stickers or on the last one
-		if progressCallback != nil {
-			if (i+1)%10 == 0 || i+1 == totalStickers {
-				progressCallback(i+1, totalStickers)
-			}
+	var added []int
+	for position, item := range items {
+		if statuses[position] == db.JobItemAdded {
+			added = append(added, position)
+		} else {
+			leaveOut(item.UniqueID)
 		}
+	}
 
-		// Delay to avoid rate limiting (1ms between 5 stickers)
-		if i < totalStickers-1 && i%5 != 0 {
-			time.Sleep(time.Millisecond)
+	stickerSet, err := bot.StickerSet(job.SetName)
+	if err != nil || len(stickerSet.Stickers) < len(added) {
+		log.Printf("Failed to fetch set %s to record its items: %v", job.SetName, err)
+	} else {
+		copies := stickerSet.Stickers[len(stickerSet.Stickers)-len(added):]
+		for i, position := range added {
+			packItems = append(packItems, db.PackItem{
+				UserID:         job.UserID,
+				PackName:       job.SetName,
+				SourceUniqueID: items[position].UniqueID,
+				CopyUniqueID:   copies[i].UniqueID,
+			})
 		}
 	}
 
-	if repo != nil {
-		pack := &db.Pack{
-			UserID:       userID,
-			PackName:     setName,
-			PackTitle:    title,
-			PackType:     dbPackType,
-			PackLink:     packLink,
-			StickerCount: len(downloadedStickers),
+	if err := repo.SavePackItems(packItems); err != nil {
+		log.Printf("Failed to record items of set %s: %v", job.SetName, err)
+	}
+}
+
+func nameTakenError(job *db.Job) *utils.BotError {
+	log.Printf("Sticker set name already exists: %s for user %d", job.SetName, job.UserID)
+	return utils.NewBotError(
+		fmt.Sprintf("Sticker set name already exists: %s", job.SetName),
+		"name-taken",
+		"STICKER_SET_NAME_TAKEN",
+	)
+}
+
+func copyCancelledError(job *db.Job) *utils.BotError {
+	return utils.NewBotError(
+		fmt.Sprintf("Copy of %s cancelled after %d/%d items", job.SetName, job.AddedCount, job.TotalCount),
+		"copy-cancelled",
+		"COPY_CANCELLED",
+	)
+}
+
+func recordJobItem(repo *db.Repository, job *db.Job, position int, sticker tg.Sticker, strategy types.CopyStrategy, duration time.Duration) {
+	item := &db.JobItem{
+		JobID:        job.ID,
+		Position:     position,
+		FileUniqueID: sticker.UniqueID,
+		Status:       db.JobItemAdded,
+		Strategy:     string(strategy),
+		DurationMs:   duration.Milliseconds(),
+	}
+	if err := repo.RecordJobItem(item); err != nil {
+		log.Printf("Failed to record item %d of job %d: %v", position, job.ID, err)
+		return
+	}
+	job.AddedCount++
+}
+
+// placeSticker keeps the set in source order when an item is added after
+// items that follow it, as happens when failed items are retried. The sticker
+// that was just appended is moved in front of those items. The job's items are
+// expected to be the last ones of the set, after any it held before the job.
+func placeSticker(bot *tg.Bot, setName string, position int, statuses map[int]db.JobItemStatus) {
+	target, total := 0, 0
+	for p, status := range statuses {
+		if status != db.JobItemAdded {
+			continue
 		}
-		if err := repo.CreatePack(pack); err != nil {
-			log.Printf("Failed to save pack to database: %v", err)
+		total++
+		if p < position {
+			target++
 		}
 	}
 
-	return packLink, nil
+	if target == total-1 {
+		return
+	}
+
+	stickerSet, err := bot.StickerSet(setName)
+	if err != nil || len(stickerSet.Stickers) < total {
+		log.Printf("Failed to fetch set %s to reorder sticker %d: %v", setName, position+1, err)
+		return
+	}
+
+	target += len(stickerSet.Stickers) - total
+	last := stickerSet.Stickers[len(stickerSet.Stickers)-1]
+	if err := bot.SetStickerPosition(last.FileID, target); err != nil {
+		log.Printf("Failed to move sticker %d to position %d in %s: %v", position+1, target, setName, err)
+	}
+}
+
+func recordJobFailure(repo *db.Repository, job *db.Job, position int, sticker tg.Sticker, reason types.FailureReason) {
+	item := &db.JobItem{
+		JobID:        job.ID,
+		Position:     position,
+		FileUniqueID: sticker.UniqueID,
+		Status:       db.JobItemFailed,
+		Reason:       string(reason),
+	}
+	if err := repo.RecordJobItem(item); err != nil {
+		log.Printf("Failed to record failed item %d of job %d: %v", position, job.ID, err)
+	}
+}
+
+// uploadFailureReason tells files Telegram considers malformed apart from
+// other rejections.
+func uploadFailureReason(err error) types.FailureReason {
+	errStr := strings.ToUpper(err.Error())
+	for _, marker := range []string{"INVALID", "DIMENSIONS", "TOO_BIG", "TOO BIG", "LONG", "WRONG FILE", "FORMAT"} {
+		if strings.Contains(errStr, marker) {
+			return types.FailureFormatInvalid
+		}
+	}
+	return types.FailureUploadRejected
 }
 
 func isNameTakenError(err error) bool {
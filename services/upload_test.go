@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"tg-sticker-stiller-bot/db"
+	"tg-sticker-stiller-bot/services/media"
+	"tg-sticker-stiller-bot/types"
+	"tg-sticker-stiller-bot/utils"
+
+	tg "gopkg.in/telebot.v4"
+)
+
+// fakeBotAPI serves the Bot API methods a copy uses. Sets are created or
+// extended with any file_id except the rejected ones; rejecting "attach://"
+// rejects files uploaded from disk. Every file downloads as file, or as bytes
+// that are no valid sticker when file is nil.
+type fakeBotAPI struct {
+	rejected map[string]bool
+	file     []byte
+	created  atomic.Bool
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/file/") {
+		if f.file == nil {
+			io.WriteString(w, "not a sticker")
+		} else {
+			w.Write(f.file)
+		}
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	reply := func(result any) {
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+	}
+
+	switch filepath.Base(r.URL.Path) {
+	case "createNewStickerSet", "addStickerToSet":
+		for fileID := range f.rejected {
+			if strings.Contains(string(body), fileID) {
+				json.NewEncoder(w).Encode(map[string]any{
+					"ok":          false,
+					"error_code":  400,
+					"description": "Bad Request: STICKER_FILE_INVALID",
+				})
+				return
+			}
+		}
+		f.created.Store(true)
+		reply(true)
+	case "getFile":
+		reply(map[string]any{"file_id": "file", "file_unique_id": "file", "file_path": "stickers/file"})
+	case "getStickerSet":
+		reply(map[string]any{
+			"name":         "copy_by_bot",
+			"title":        "Copy",
+			"sticker_type": "regular",
+			"stickers": []map[string]any{
+				{"file_id": "copy", "file_unique_id": "copy", "type": "regular", "width": 512, "height": 512},
+			},
+		})
+	default:
+		reply(true)
+	}
+}
+
+// copyJob runs a copy of items into a new set of stickerType against api and
+// returns its result.
+func copyJob(t *testing.T, api *fakeBotAPI, stickerType types.StickerType, items []types.CopyItem) (*types.CopyResult, error) {
+	t.Chdir(t.TempDir())
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	bot, err := tg.NewBot(tg.Settings{URL: server.URL, Token: "token", Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := db.NewRepository(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	job, err := NewCopyJob(repo, 1, "en", "copy_by_bot", "Copy", items, stickerType, DefaultFallbackEmoji, []string{"source"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return CreateStickerSet(context.Background(), bot, job, items, repo, nil)
+}
+
+func copyItemOf(fileID string, sticker tg.Sticker) types.CopyItem {
+	sticker.File = tg.File{FileID: fileID, UniqueID: fileID}
+	sticker.Emoji = "🙂"
+	return types.CopyItem{Sticker: sticker}
+}
+
+func TestCreateStickerSetSkipsInvalidFirstItem(t *testing.T) {
+	api := &fakeBotAPI{rejected: map[string]bool{"invalid": true}}
+	items := []types.CopyItem{
+		copyItemOf("invalid", tg.Sticker{Type: tg.StickerRegular}),
+		copyItemOf("valid", tg.Sticker{Type: tg.StickerRegular}),
+	}
+
+	result, err := copyJob(t, api, types.StickerTypeRegular, items)
+	if err != nil {
+		t.Fatalf("CreateStickerSet() error = %v", err)
+	}
+	if !api.created.Load() {
+		t.Error("set was not created with the valid item")
+	}
+	if result.Added != 1 {
+		t.Errorf("Added = %d, want 1", result.Added)
+	}
+	want := types.FailedItem{Position: 0, Emoji: "🙂", Reason: types.FailureFormatInvalid}
+	if len(result.Failed) != 1 || result.Failed[0] != want {
+		t.Errorf("Failed = %v, want %v", result.Failed, want)
+	}
+}
+
+func TestCreateStickerSetSkipsUnconvertibleFirstItem(t *testing.T) {
+	api := &fakeBotAPI{}
+	items := []types.CopyItem{
+		copyItemOf("animated", tg.Sticker{Type: tg.StickerRegular, Animated: true}),
+		copyItemOf("emoji", tg.Sticker{Type: tg.StickerCustomEmoji}),
+	}
+
+	result, err := copyJob(t, api, types.StickerTypeEmoji, items)
+	if err != nil {
+		t.Fatalf("CreateStickerSet() error = %v", err)
+	}
+	if result.Added != 1 {
+		t.Errorf("Added = %d, want 1", result.Added)
+	}
+	want := types.FailedItem{Position: 0, Emoji: "🙂", Reason: types.FailureFormatInvalid}
+	if len(result.Failed) != 1 || result.Failed[0] != want {
+		t.Errorf("Failed = %v, want %v", result.Failed, want)
+	}
+}
+
+// staticSticker returns a lossless WebP header of a 512×512 sticker.
+func staticSticker() []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x0a\x00\x00\x00\x2f")
+	data = binary.LittleEndian.AppendUint32(data, 511|511<<14)
+	data = append(data, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	return data
+}
+
+func TestCreateStickerSetFailsWhenTelegramRejectsTheSet(t *testing.T) {
+	api := &fakeBotAPI{rejected: map[string]bool{"first": true, "attach://": true}, file: staticSticker()}
+	items := []types.CopyItem{
+		copyItemOf("first", tg.Sticker{Type: tg.StickerRegular}),
+		copyItemOf("second", tg.Sticker{Type: tg.StickerRegular}),
+	}
+
+	if _, err := copyJob(t, api, types.StickerTypeRegular, items); err == nil {
+		t.Error("CreateStickerSet() error = nil, want an error")
+	}
+	if api.created.Load() {
+		t.Error("set was created after Telegram refused to create it")
+	}
+}
+
+// animatedSticker returns a TGS file with a size×size canvas.
+func animatedSticker(size int) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	json.NewEncoder(writer).Encode(map[string]any{"fr": 60, "ip": 0, "op": 60, "w": size, "h": size, "layers": []any{}})
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestPrepareUpload(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	animated := tg.Sticker{Type: tg.StickerRegular, Animated: true}
+	static := tg.Sticker{Type: tg.StickerRegular}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		sticker   tg.Sticker
+		wantFixed bool
+		wantErr   bool
+	}{
+		{"valid file is kept", animatedSticker(512), animated, false, false},
+		{"wrong canvas is fixed", animatedSticker(100), animated, true, false},
+		{"oversized file is rejected", append(staticSticker(), make([]byte, 512*1024)...), static, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sticker")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			uploadPath, err := prepareUpload(context.Background(), path, tt.sticker, types.StickerTypeRegular)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prepareUpload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if fixed := uploadPath != path; fixed != tt.wantFixed {
+				t.Errorf("fixed = %v, want %v", fixed, tt.wantFixed)
+			}
+			if err := media.Validate(uploadPath, utils.GetStickerFormat(tt.sticker), false); err != nil {
+				t.Errorf("prepared file is invalid: %v", err)
+			}
+		})
+	}
+}
@@ -6,15 +6,64 @@ type StickerType string
 
 const (
 	StickerTypeRegular StickerType = "regular"
+	StickerTypeMask    StickerType = "mask"
 	StickerTypeEmoji   StickerType = "custom_emoji"
 )
 
+// CopyItem is a source sticker together with the metadata carried over to
+// its copy.
+type CopyItem struct {
+	tg.Sticker
+	Emojis   []string `json:"emoji_list,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
 type DownloadedSticker struct {
-	Path    string
-	Sticker tg.Sticker
+	Path     string
+	Position int
+	Sticker  tg.Sticker
 }
 
 type DownloadedEmoji struct {
 	Path  string
 	Emoji tg.Sticker
 }
+
+type FailureReason string
+
+const (
+	FailureDownload       FailureReason = "download_failed"
+	FailureUploadRejected FailureReason = "upload_rejected"
+	FailureFormatInvalid  FailureReason = "format_invalid"
+	FailureSetFull        FailureReason = "set_full"
+
+	// Reasons archive entries are skipped for when importing
+	FailureMissingFile FailureReason = "missing_file"
+	FailureUnsupported FailureReason = "unsupported_type"
+	FailureTooLarge    FailureReason = "file_too_large"
+	FailureExtract     FailureReason = "extract_failed"
+)
+
+// CopyStrategy is how a sticker was handed to Telegram when copying it.
+type CopyStrategy string
+
+const (
+	StrategyFileID  CopyStrategy = "file_id"
+	StrategyUpload  CopyStrategy = "upload"
+	StrategyConvert CopyStrategy = "convert"
+)
+
+// FailedItem is a source sticker that could not be copied.
+type FailedItem struct {
+	Position int
+	Emoji    string
+	Reason   FailureReason
+}
+
+// CopyResult describes the outcome of a finished copy.
+type CopyResult struct {
+	PackLink string
+	Added    int
+	Total    int
+	Failed   []FailedItem
+}
@@ -16,12 +16,7 @@ type TelegramAPIResponse struct {
 type StickerSet struct {
 	Name     string       `json:"name"`
 	Title    string       `json:"title"`
-	Stickers []tg.Sticker `json:"stickers"`
-}
-
-type EmojiSet struct {
-	Name     string       `json:"name"`
-	Title    string       `json:"title"`
+	Type     StickerType  `json:"sticker_type"`
 	Stickers []tg.Sticker `json:"stickers"`
 }
 
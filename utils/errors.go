@@ -25,6 +25,12 @@ func NewBotError(message, i18nKey, errorCode string) *BotError {
 	}
 }
 
+// IsBotError reports whether err is a BotError with the given i18n key
+func IsBotError(err error, i18nKey string) bool {
+	botErr, ok := err.(*BotError)
+	return ok && botErr.I18nKey == i18nKey
+}
+
 // FailFast panics if error is not nil
 func FailFast(err error) {
 	log.Printf("Error: %v", err)
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ukrainianLetters follows the official Ukrainian romanization of 2010.
+var ukrainianLetters = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie",
+	'ж': "zh", 'з': "z", 'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l",
+	'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ь': "", 'ю': "iu",
+	'я': "ia",
+}
+
+// ukrainianInitials replaces ukrainianLetters at the start of a word.
+var ukrainianInitials = map[rune]string{
+	'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya",
+}
+
+var russianLetters = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+var latinLetters = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g", 'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j", 'ķ': "k", 'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ŏ': "o", 'ő': "o",
+	'œ': "oe", 'ŕ': "r", 'ŗ': "r", 'ř': "r", 'ś': "s", 'ŝ': "s", 'ş': "s", 'š': "s", 'ș': "s",
+	'ß': "ss", 'ţ': "t", 'ť': "t", 'ŧ': "t", 'ț': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ŵ': "w", 'ý': "y", 'ÿ': "y", 'ŷ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// Transliterate spells Cyrillic and accented Latin letters of text with
+// plain Latin letters and returns it in lowercase. Cyrillic text is read as
+// Ukrainian unless it contains letters only Russian uses. Apostrophes are
+// dropped; other characters are kept as they are.
+func Transliterate(text string) string {
+	runes := []rune(strings.ToLower(text))
+
+	ukrainian := !isRussian(runes)
+	cyrillic := ukrainianLetters
+	if !ukrainian {
+		cyrillic = russianLetters
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		if isApostrophe(r) {
+			continue
+		}
+
+		wordStart := i == 0 || !(unicode.IsLetter(runes[i-1]) || isApostrophe(runes[i-1]))
+		if ukrainian {
+			if initial, ok := ukrainianInitials[r]; ok && wordStart {
+				b.WriteString(initial)
+				continue
+			}
+			// "зг" is spelled "zgh" to keep it apart from "ж"
+			if r == 'г' && i > 0 && runes[i-1] == 'з' {
+				b.WriteString("gh")
+				continue
+			}
+		}
+
+		if latin, ok := cyrillic[r]; ok {
+			b.WriteString(latin)
+		} else if latin, ok := latinLetters[r]; ok {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func isRussian(runes []rune) bool {
+	russian := false
+	for _, r := range runes {
+		switch r {
+		case 'є', 'і', 'ї', 'ґ':
+			return false
+		case 'ё', 'ъ', 'ы', 'э':
+			russian = true
+		}
+	}
+	return russian
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’' || r == 'ʼ'
+}
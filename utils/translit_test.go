@@ -0,0 +1,179 @@
+package utils
+
+import "testing"
+
+func TestTransliterateUkrainianLetters(t *testing.T) {
+	tests := []struct {
+		letter string
+		want   string
+	}{
+		{"а", "a"}, {"б", "b"}, {"в", "v"}, {"г", "h"}, {"ґ", "g"}, {"д", "d"},
+		{"е", "e"}, {"є", "ie"}, {"ж", "zh"}, {"з", "z"}, {"и", "y"}, {"і", "i"},
+		{"ї", "i"}, {"й", "i"}, {"к", "k"}, {"л", "l"}, {"м", "m"}, {"н", "n"},
+		{"о", "o"}, {"п", "p"}, {"р", "r"}, {"с", "s"}, {"т", "t"}, {"у", "u"},
+		{"ф", "f"}, {"х", "kh"}, {"ц", "ts"}, {"ч", "ch"}, {"ш", "sh"}, {"щ", "shch"},
+		{"ь", ""}, {"ю", "iu"}, {"я", "ia"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.letter, func(t *testing.T) {
+			// Placed inside a word so initial spellings don't apply
+			got := Transliterate("і" + tt.letter)
+			if want := "i" + tt.want; got != want {
+				t.Errorf("Transliterate(%q) = %q, want %q", "і"+tt.letter, got, want)
+			}
+		})
+	}
+}
+
+func TestTransliterateUkrainianWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Алушта", "alushta"},
+		{"Борщагівка", "borshchahivka"},
+		{"Вінниця", "vinnytsia"},
+		{"Гадяч", "hadiach"},
+		{"Згорани", "zghorany"},
+		{"Ґалаґан", "galagan"},
+		{"Єнакієве", "yenakiieve"},
+		{"Наєнко", "naienko"},
+		{"Житомир", "zhytomyr"},
+		{"Закарпаття", "zakarpattia"},
+		{"Іршава", "irshava"},
+		{"Їжакевич", "yizhakevych"},
+		{"Кадіївка", "kadiivka"},
+		{"Йосипівка", "yosypivka"},
+		{"Стрий", "stryi"},
+		{"Олексій", "oleksii"},
+		{"Київ", "kyiv"},
+		{"Миколаїв", "mykolaiv"},
+		{"Ужгород", "uzhhorod"},
+		{"Харків", "kharkiv"},
+		{"Біла Церква", "bila tserkva"},
+		{"Чернівці", "chernivtsi"},
+		{"Гоща", "hoshcha"},
+		{"Русь", "rus"},
+		{"Юрій", "yurii"},
+		{"Крюківка", "kriukivka"},
+		{"Яготин", "yahotyn"},
+		{"Ічня", "ichnia"},
+		{"Знам'янка", "znamianka"},
+		{"Знам’янка", "znamianka"},
+		{"абвгґдеєжзиіїйклмнопрстуфхцчшщьюя", "abvhgdeiezhzyiiiklmnoprstufkhtschshshchiuia"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Transliterate(tt.input); got != tt.want {
+				t.Errorf("Transliterate(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransliterateRussianLetters(t *testing.T) {
+	tests := []struct {
+		letter string
+		want   string
+	}{
+		{"а", "a"}, {"б", "b"}, {"в", "v"}, {"г", "g"}, {"д", "d"}, {"е", "e"},
+		{"ё", "e"}, {"ж", "zh"}, {"з", "z"}, {"и", "i"}, {"й", "y"}, {"к", "k"},
+		{"л", "l"}, {"м", "m"}, {"н", "n"}, {"о", "o"}, {"п", "p"}, {"р", "r"},
+		{"с", "s"}, {"т", "t"}, {"у", "u"}, {"ф", "f"}, {"х", "kh"}, {"ц", "ts"},
+		{"ч", "ch"}, {"ш", "sh"}, {"щ", "shch"}, {"ъ", ""}, {"ы", "y"}, {"ь", ""},
+		{"э", "e"}, {"ю", "yu"}, {"я", "ya"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.letter, func(t *testing.T) {
+			// "ы" marks the text as Russian
+			got := Transliterate("ы" + tt.letter)
+			if want := "y" + tt.want; got != want {
+				t.Errorf("Transliterate(%q) = %q, want %q", "ы"+tt.letter, got, want)
+			}
+		})
+	}
+}
+
+func TestTransliterateRussianWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Объявление", "obyavlenie"},
+		{"Съёмка", "semka"},
+		{"Мышь", "mysh"},
+		{"Эхо", "ekho"},
+		{"абвгдеёжзийклмнопрстуфхцчшщъыьэюя", "abvgdeezhziyklmnoprstufkhtschshshchyeyuya"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Transliterate(tt.input); got != tt.want {
+				t.Errorf("Transliterate(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransliterateLatinDiacritics(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"àáâãäåāăą", "aaaaaaaaa"},
+		{"çćĉċč", "ccccc"},
+		{"ďđð", "ddd"},
+		{"èéêëēĕėęě", "eeeeeeeee"},
+		{"ĝğġģ", "gggg"},
+		{"ĥħ", "hh"},
+		{"ìíîïĩīĭįı", "iiiiiiiii"},
+		{"ĵķ", "jk"},
+		{"ĺļľŀł", "lllll"},
+		{"ñńņň", "nnnn"},
+		{"òóôõöøōŏő", "ooooooooo"},
+		{"ŕŗř", "rrr"},
+		{"śŝşšș", "sssss"},
+		{"ţťŧț", "tttt"},
+		{"ùúûüũūŭůűų", "uuuuuuuuuu"},
+		{"ŵýÿŷ", "wyyy"},
+		{"źżž", "zzz"},
+		{"æœßþ", "aeoessth"},
+		{"Crème Brûlée", "creme brulee"},
+		{"Łódź", "lodz"},
+		{"Straße", "strasse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Transliterate(tt.input); got != tt.want {
+				t.Errorf("Transliterate(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePackName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"My Cats", "my_cats"},
+		{"  Котики 2  ", "kotyky_2"},
+		{"Їжачок і Ко", "yizhachok_i_ko"},
+		{"Съёмка!", "semka"},
+		{"Café — Ñandú", "cafe_nandu"},
+		{"😀 Emoji 😀", "emoji"},
+		{"日本語", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := NormalizePackName(tt.input); got != tt.want {
+				t.Errorf("NormalizePackName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -77,3 +77,16 @@ func CleanupFiles(filePaths []string) {
 
 	wg.Wait()
 }
+
+// CustomEmojiIDs returns the custom emoji used in a message text or caption.
+func CustomEmojiIDs(msg *tg.Message) []string {
+	var ids []string
+	for _, entities := range []tg.Entities{msg.Entities, msg.CaptionEntities} {
+		for _, entity := range entities {
+			if entity.Type == tg.EntityCustomEmoji && entity.CustomEmojiID != "" {
+				ids = append(ids, entity.CustomEmojiID)
+			}
+		}
+	}
+	return ids
+}
@@ -1,13 +1,20 @@
 package utils
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
-	stickerPackRegex = regexp.MustCompile("(?:https?://)?(?:www.)?t.me/addstickers/([a-zA-Z0-9_]+)")
-	emojiPackRegex   = regexp.MustCompile("(?:https?://)?(?:www.)?t.me/addemoji/([a-zA-Z0-9_]+)")
+	stickerPackRegex = regexp.MustCompile(`(?:(?:https?://)?(?:www\.)?(?:t|telegram)\.me/addstickers/|tg://addstickers\?set=)([a-zA-Z0-9_]+)`)
+	emojiPackRegex   = regexp.MustCompile(`(?:(?:https?://)?(?:www\.)?(?:t|telegram)\.me/addemoji/|tg://addemoji\?set=)([a-zA-Z0-9_]+)`)
+	anyPackRegex     = regexp.MustCompile(`(?:(?:https?://)?(?:www\.)?(?:t|telegram)\.me/add(?:stickers|emoji)/|tg://add(?:stickers|emoji)\?set=)([a-zA-Z0-9_]+)`)
+	rangeDashRegex   = regexp.MustCompile(`\s*-\s*`)
 )
 
 func IsStickerPack(text string) bool {
@@ -34,10 +41,56 @@ func ExtractEmojiPackName(text string) string {
 	return ""
 }
 
+// ExtractPackNames returns the set names of every sticker and emoji pack link
+// in text, in order and without duplicates. The link kind is ignored; the
+// fetched set decides the pack type.
+func ExtractPackNames(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, matches := range anyPackRegex.FindAllStringSubmatch(text, -1) {
+		name := matches[1]
+		if !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// AutoPackName derives a set name for a copy from the source pack, preferring
+// its title and falling back to the source set name without its bot suffix.
+// The name is shortened to fit the set name limit of botUsername.
+func AutoPackName(title, sourceName, botUsername string) string {
+	if name := NormalizePackName(title); name != "" {
+		return truncatePackName(name, MaxPackNameLength(botUsername))
+	}
+
+	if i := strings.Index(strings.ToLower(sourceName), "_by_"); i > 0 {
+		sourceName = sourceName[:i]
+	}
+	return truncatePackName(NormalizePackName(sourceName), MaxPackNameLength(botUsername))
+}
+
+// SuffixPackName appends "_<suffix>" to name, shortening name when needed so
+// that the set name built from it still fits the limit.
+func SuffixPackName(name, suffix, botUsername string) string {
+	name = truncatePackName(name, MaxPackNameLength(botUsername)-len(suffix)-1)
+	return name + "_" + suffix
+}
+
+func truncatePackName(name string, maxLength int) string {
+	if len(name) > maxLength {
+		name = strings.TrimRight(name[:maxLength], "_")
+	}
+	return name
+}
+
 func NormalizePackName(input string) string {
-	// Convert to lowercase
+	// Convert to lowercase Latin letters
 	normalized := regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(
-		regexp.MustCompile(`\s+`).ReplaceAllString(strings.ToLower(strings.TrimSpace(input)), "_"),
+		regexp.MustCompile(`\s+`).ReplaceAllString(Transliterate(strings.TrimSpace(input)), "_"),
 		"_",
 	)
 
@@ -50,8 +103,18 @@ func NormalizePackName(input string) string {
 	return normalized
 }
 
-func ValidateNormalizedName(name string) bool {
-	if len(name) == 0 || len(name) > 64 {
+// maxSetNameLength is the longest set name Telegram accepts, including the
+// "_by_<bot>" suffix added by GenerateSetName.
+const maxSetNameLength = 64
+
+// MaxPackNameLength returns how long a pack name may be before the bot
+// suffix is appended.
+func MaxPackNameLength(botUsername string) int {
+	return maxSetNameLength - len(GenerateSetName("", botUsername))
+}
+
+func ValidateNormalizedName(name, botUsername string) bool {
+	if len(name) == 0 || len(name) > MaxPackNameLength(botUsername) {
 		return false
 	}
 
@@ -60,13 +123,92 @@ func ValidateNormalizedName(name string) bool {
 	return validNameRegex.MatchString(name)
 }
 
-func GetValidationError(name string) string {
+func GetValidationError(name, botUsername string) string {
 	if len(name) == 0 {
 		return "name-empty"
 	}
-	if len(name) > 64 {
+	if len(name) > MaxPackNameLength(botUsername) {
 		return "name-too-long"
 	}
 
 	return ""
 }
+
+// MaxTitleLength is the longest set title Telegram accepts, in characters.
+const MaxTitleLength = 64
+
+// GetTitleValidationError returns the i18n key describing why title can't be
+// used as a set title, or an empty string when it can.
+func GetTitleValidationError(title string) string {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "title-empty"
+	}
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return "title-too-long"
+	}
+
+	return ""
+}
+
+// IsEmoji reports whether text looks like a single emoji: a short run of
+// non-ASCII symbols without letters, digits or spaces.
+func IsEmoji(text string) bool {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) == 0 || len(runes) > 10 {
+		return false
+	}
+
+	for _, r := range runes {
+		if r < unicode.MaxASCII || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseSelection parses a list of item numbers and ranges such as
+// "1-10,15,20-25" for a pack of total items. It returns the selected
+// zero-based positions in ascending order without duplicates.
+func ParseSelection(input string, total int) ([]int, error) {
+	selected := make(map[int]bool)
+	input = rangeDashRegex.ReplaceAllString(input, "-")
+
+	for _, part := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		from, to, isRange := strings.Cut(part, "-")
+
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item number %q", from)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid item number %q", to)
+			}
+		}
+
+		if first > last {
+			return nil, fmt.Errorf("invalid range %q", part)
+		}
+		if first < 1 || last > total {
+			return nil, fmt.Errorf("range %q is outside 1-%d", part, total)
+		}
+		for i := first; i <= last; i++ {
+			selected[i-1] = true
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no items selected")
+	}
+
+	positions := make([]int, 0, len(selected))
+	for position := range selected {
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+
+	return positions, nil
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMaxPackNameLength(t *testing.T) {
+	tests := []struct {
+		botUsername string
+		want        int
+	}{
+		{"a_bot", 55},
+		{"stiller_bot", 49},
+		{"sticker_and_emoji_stiller_bot", 31},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.botUsername, func(t *testing.T) {
+			got := MaxPackNameLength(tt.botUsername)
+			if got != tt.want {
+				t.Errorf("MaxPackNameLength(%q) = %d, want %d", tt.botUsername, got, tt.want)
+			}
+			if setName := GenerateSetName(strings.Repeat("a", got), tt.botUsername); len(setName) != maxSetNameLength {
+				t.Errorf("set name %q is %d characters, want %d", setName, len(setName), maxSetNameLength)
+			}
+		})
+	}
+}
+
+func TestSuffixPackName(t *testing.T) {
+	const botUsername = "stiller_bot"
+	long := strings.Repeat("a", 49)
+
+	tests := []struct {
+		name   string
+		input  string
+		suffix string
+		want   string
+	}{
+		{"short name", "cats", "2", "cats_2"},
+		{"random suffix", "cats", "x7k2", "cats_x7k2"},
+		{"name at the limit", long, "2", strings.Repeat("a", 47) + "_2"},
+		{"longer suffix", long, "x7k2", strings.Repeat("a", 44) + "_x7k2"},
+		{"cut at an underscore", strings.Repeat("a", 46) + "_bc", "2", strings.Repeat("a", 46) + "_2"},
+		{"cut before underscores", strings.Repeat("a", 43) + "____cc", "10", strings.Repeat("a", 43) + "_10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SuffixPackName(tt.input, tt.suffix, botUsername)
+			if got != tt.want {
+				t.Errorf("SuffixPackName(%q, %q) = %q, want %q", tt.input, tt.suffix, got, tt.want)
+			}
+			if len(got) > MaxPackNameLength(botUsername) {
+				t.Errorf("SuffixPackName(%q, %q) is %d characters, at most %d allowed", tt.input, tt.suffix, len(got), MaxPackNameLength(botUsername))
+			}
+		})
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		total   int
+		want    []int
+		wantErr bool
+	}{
+		{name: "single item", input: "3", total: 10, want: []int{2}},
+		{name: "ranges and items", input: "1-3,5,8-9", total: 10, want: []int{0, 1, 2, 4, 7, 8}},
+		{name: "whole pack", input: "1-4", total: 4, want: []int{0, 1, 2, 3}},
+		{name: "single item range", input: "4-4", total: 4, want: []int{3}},
+		{name: "unsorted input", input: "9,2-3,1", total: 10, want: []int{0, 1, 2, 8}},
+		{name: "duplicate items", input: "2,2,2", total: 5, want: []int{1}},
+		{name: "overlapping ranges", input: "1-4,3-6,5", total: 10, want: []int{0, 1, 2, 3, 4, 5}},
+		{name: "spaces around separators", input: " 1 - 3 , 5 ", total: 10, want: []int{0, 1, 2, 4}},
+		{name: "spaces instead of commas", input: "1 3\t5\n7", total: 10, want: []int{0, 2, 4, 6}},
+		{name: "empty parts", input: ",,2,,", total: 5, want: []int{1}},
+		{name: "zero", input: "0", total: 5, wantErr: true},
+		{name: "past the end", input: "6", total: 5, wantErr: true},
+		{name: "range past the end", input: "3-6", total: 5, wantErr: true},
+		{name: "range before the start", input: "0-2", total: 5, wantErr: true},
+		{name: "reversed range", input: "5-2", total: 10, wantErr: true},
+		{name: "negative number", input: "-2", total: 10, wantErr: true},
+		{name: "open range", input: "3-", total: 10, wantErr: true},
+		{name: "not a number", input: "first", total: 10, wantErr: true},
+		{name: "empty input", input: "", total: 10, wantErr: true},
+		{name: "only separators", input: " , ", total: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSelection(tt.input, tt.total)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseSelection(%q, %d) = %v, want an error", tt.input, tt.total, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSelection(%q, %d) failed: %v", tt.input, tt.total, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseSelection(%q, %d) = %v, want %v", tt.input, tt.total, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPackNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"sticker link", "https://t.me/addstickers/Cats", []string{"Cats"}},
+		{"emoji link", "https://t.me/addemoji/CatEmoji", []string{"CatEmoji"}},
+		{"link without scheme", "t.me/addstickers/cats_by_bot", []string{"cats_by_bot"}},
+		{"telegram.me link", "http://www.telegram.me/addstickers/Dogs", []string{"Dogs"}},
+		{"tg links", "tg://addstickers?set=Cats tg://addemoji?set=Dogs", []string{"Cats", "Dogs"}},
+		{"several links", "https://t.me/addstickers/Cats\nhttps://t.me/addemoji/Dogs\nhttps://t.me/addstickers/Birds", []string{"Cats", "Dogs", "Birds"}},
+		{"duplicates", "t.me/addstickers/Cats t.me/addstickers/Cats t.me/addemoji/Cats", []string{"Cats"}},
+		{"duplicates in another case", "t.me/addstickers/Cats t.me/addstickers/cats", []string{"Cats"}},
+		{"mixed text", "Look at these: t.me/addstickers/Cats, and (t.me/addemoji/Dogs)!", []string{"Cats", "Dogs"}},
+		{"bare name", "Cats", nil},
+		{"bare names", "Cats Dogs cats_by_bot", nil},
+		{"other links", "https://t.me/durov https://example.com/addstickers/Cats", nil},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractPackNames(tt.input)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExtractPackNames(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}